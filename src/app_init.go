@@ -4,31 +4,44 @@ package main
 import (
 	"math/rand"
 	"time"
+
+	"myrpg/world2"
 )
 
 // initializeNewGame は Game 構造体の初期値を設定する
 func initializeNewGame() *Game {
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	defaults := world2.DefaultConfig()
 	
 	return &Game{ 
 		State: StateMenu, 
 		MenuIndex: 0, 
 		Rng: rng,
 		
-		SoilMin: 20,
-		SoilMax: 28,
-		W2Width: DefaultWorld2Width,
-		W2Height: DefaultWorld2Height,
-		TransitDist: 15,
-		VastOceanSize: 25,
-		IslandBoundSize: 15,
-		MapRatio:    10,
-		EnableCentering: true,
+		SoilMin: defaults.MinPct,
+		SoilMax: defaults.MaxPct,
+		W2Width: defaults.W,
+		W2Height: defaults.H,
+		TransitDist: defaults.TransitDist,
+		VastOceanSize: defaults.VastOcean,
+		IslandBoundSize: defaults.IslandBound,
+		MapRatio:    defaults.Ratio,
+		MainType:    defaults.MainType,
+		SubType:     defaults.SubType,
+		EnableCentering: defaults.Centering,
+		EnableIslandShallow: defaults.IslandShallow,
 
-		CliffInitVal:  10.0,
-		CliffDecVal:   0.1,
-		ShallowDecVal: 0.25,
-		CliffPathLen:  5, 
-		ForceSwitch:   5, 
+		CliffInitVal:  defaults.CliffInit,
+		CliffDecVal:   defaults.CliffDec,
+		ShallowDecVal: defaults.ShallowDec,
+		CliffPathLen:  defaults.CliffPathLen, 
+		ForceSwitch:   defaults.ForceSwitch, 
+		RiverCount:    defaults.RiverCount,
+		RiverMinLen:   defaults.RiverMinLen,
+		NationCount:   defaults.NationCount,
+		TownCount:     defaults.TownCount,
+		W2MoveCosts:   world2.DefaultMoveCosts(),
+		W2SeedSearch:  "islands>=1, lakes>=1, largest>60",
+		W2SeedTries:   200,
 	}
 }
\ No newline at end of file
@@ -0,0 +1,146 @@
+// filename: cmd/world2gen/main.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"myrpg/world2"
+)
+
+// world2gen は Phase_Init から Phase_Settlements までをウィンドウなしで実行し、
+// シードごとの結果をファイルに書き出す
+// -find を指定すると条件を満たすシードだけを探して count 個書き出す (シード探索モード)
+func main() {
+	settingsPath := flag.String("settings", "settings.txt", "settings file (same keys as the game)")
+	startSeed := flag.Int64("seed", 1, "first seed (settings Seed: 0 picks a random one, -seed 0 is seed 0)")
+	count := flag.Int("count", 1, "number of consecutive seeds to generate")
+	outDir := flag.String("out", "world2_out", "output directory")
+	format := flag.String("format", "txt", "output format: txt, json or bin")
+	pngScale := flag.Int("png", 0, "also write seed_N.png with this many pixels per tile (0 = off)")
+	sheet := flag.Bool("sheet", false, "also write seed_N_sheet.png, a contact sheet of every phase snapshot")
+	find := flag.String("find", "", `seed search: only write maps meeting constraints such as "islands=3,lakes>=1,largest>60"`)
+	tries := flag.Int("tries", 1000, "seed search: maximum number of seeds to try")
+	flag.Parse()
+
+	cfg := world2.DefaultConfig()
+	settings, err := world2.LoadSettings(*settingsPath)
+	if err != nil {
+		log.Fatalf("Error loading settings: %v", err)
+	}
+	cfg.ApplySettings(settings)
+
+	// -seed が明示されていなければ settings.txt の Seed を開始シードに使う
+	// ゲームと同じく Seed: 0 はランダムなシードの意味なので、選んだシードを表示して再現できるようにする
+	seedSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seedSet = true
+		}
+	})
+	if !seedSet {
+		world2.ApplyInt64Setting(settings, "Seed", startSeed)
+		if *startSeed == 0 {
+			// 連番で使うシードが int64 を超えないように、試す数だけ手前から選ぶ
+			span := int64(max(*count, *tries))
+			*startSeed = rand.Int63n(math.MaxInt64 - span)
+			fmt.Printf("settings Seed: 0, using random start seed %d (pass -seed %d to reproduce)\n", *startSeed, *startSeed)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	ext, ok := map[string]string{"txt": ".txt", "json": ".json", "bin": ".w2m"}[*format]
+	if !ok {
+		log.Fatalf("unknown format %q", *format)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	constraints, err := world2.ParseConstraints(*find)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// output は生成済みの gen を指定の形式で書き出し、指標とともに1行表示する
+	output := func(gen *world2.World2Generator, seed int64) {
+		m := gen.World2
+
+		path := filepath.Join(*outDir, fmt.Sprintf("seed_%d%s", seed, ext))
+		var err error
+		if *format == "txt" {
+			err = writeMap(path, m)
+		} else {
+			err = world2.SaveFile(path, world2.NewSaveData(m, cfg, seed))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *pngScale > 0 {
+			if err := m.SavePNG(filepath.Join(*outDir, fmt.Sprintf("seed_%d.png", seed)), *pngScale); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *sheet {
+			sheetPath := filepath.Join(*outDir, fmt.Sprintf("seed_%d_sheet.png", seed))
+			if err := gen.SaveContactSheet(sheetPath, 5, 2); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		// StatsInfo[0] は Phase 名なので集計行のみ表示
+		stats := m.StatsInfo
+		if len(stats) > 1 {
+			stats = stats[1:]
+		}
+		stats = append(stats, m.Metrics().Lines()...)
+		fmt.Printf("seed=%d  %s  -> %s\n", seed, strings.Join(stats, "  "), path)
+	}
+
+	if *find != "" {
+		// シード探索モード: -seed から順に最大 -tries 個を試し、条件を満たしたものを -count 個まで書き出す
+		seed, left := *startSeed, *tries
+		for found := 0; found < *count; found++ {
+			tried := 0
+			gen, err := world2.SearchSeed(cfg, constraints, seed, left, nil, func(int64, world2.Metrics) { tried++ })
+			left -= tried
+			if err == world2.ErrSeedNotFound {
+				log.Fatalf("found %d of %d maps meeting %q in %d seeds", found, *count, *find, *tries)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			output(gen, gen.StartSeed)
+			seed = gen.StartSeed + 1
+		}
+		return
+	}
+
+	for i := 0; i < *count; i++ {
+		seed := *startSeed + int64(i)
+		gen, err := world2.NewGenerator(cfg, seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gen.Run()
+		output(gen, seed)
+	}
+}
+
+func writeMap(path string, m *world2.WorldMap2) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.WriteASCII(f)
+}
@@ -4,19 +4,43 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+
+	"myrpg/world2"
 )
 
 // 初期設定ファイル名
 const SettingsFilename = "settings.txt"
 
+// World2 の保存ファイル名 ([S] で両方に保存、[L] はバイナリを優先して読み込む)
+const (
+	World2SaveJSON   = "world2_save.json"
+	World2SaveBinary = "world2_save.w2m"
+)
+
+// World2ExportScale は PNG 出力時の1タイルあたりのピクセル数
+const World2ExportScale = 4
+
+// World2PreviewInterval は裏で生成している間に途中のマップを表示し直す間隔
+const World2PreviewInterval = 250 * time.Millisecond
+
+// World2 画面右側のタイムライン (全スナップショットの一覧) の位置と行の高さ
+// 右上のメモリ表示の下に置く
+const (
+	World2TimelineX    = ScreenWidth - 230
+	World2TimelineY    = 220
+	World2TimelineW    = 220
+	World2TimelineRowH = 14
+)
+
 // NewGame は app_init.go で定義されたヘルパー関数
 func NewGame() *Game {
 	g := initializeNewGame()
 	
 	// 設定ファイルを読み込み、初期値を上書き
-	if settings, err := LoadSettings(SettingsFilename); err == nil {
+	if settings, err := world2.LoadSettings(SettingsFilename); err == nil {
 		g.ApplySettings(settings)
 	} else if !os.IsNotExist(err) {
 		log.Printf("Error loading settings: %v", err)
@@ -46,8 +70,11 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	case StateDungeon: g.DrawDungeon(screen)
 	case StateWorld2: g.DrawWorld2(screen)
 	}
-	
-	DrawMemoryStats(screen)
+
+	// 生成履歴のサイズは World2 画面でのみ表示する
+	var gen *world2.World2Generator
+	if g.State == StateWorld2 { gen = g.Gen2 }
+	DrawMemoryStats(screen, gen)
 }
 
 func (g *Game) Layout(w, h int) (int, int) { return ScreenWidth, ScreenHeight }
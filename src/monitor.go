@@ -7,9 +7,12 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"myrpg/world2"
 )
 
-func DrawMemoryStats(screen *ebiten.Image) {
+// DrawMemoryStats は FPS とメモリ使用量を右上に表示する (gen があれば生成履歴のサイズも表示)
+func DrawMemoryStats(screen *ebiten.Image, gen *world2.World2Generator) {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 
@@ -38,6 +41,11 @@ func DrawMemoryStats(screen *ebiten.Image) {
 		ebiten.ActualTPS(),
 		alloc, total, sys, usagePercent,
 	)
+	if gen != nil {
+		// History: 差分とキーフレームの概算サイズ (スナップショット数 / うちキーフレーム数)
+		histBytes, keyframes := gen.HistoryStats()
+		msg += fmt.Sprintf("\n\n[History]\nSize: %v KB\nSteps: %d (Key %d)", histBytes/1024, len(gen.History), keyframes)
+	}
 
 	// 右上に表示
 	ebitenutil.DebugPrintAt(screen, msg, ScreenWidth-150, 10)
@@ -1,20 +0,0 @@
-// filename: world2/phase_transit_route1.go
-package main
-
-import (
-	"math/rand"
-	"fmt"
-)
-
-// 航路1のマークアップと円形航路のロジック
-func (g *Game) PhaseTransitRoute1(w, h int, rng *rand.Rand, gen *World2Generator) {
-	gen.PhaseName = "9. Transit Route 1 Markup"
-	
-	// 1. 航路1の総距離を計算し、g.TotalRoute1Dist に格納
-	// 複雑な計算は省略し、ここではダミー値で代替
-	g.TotalRoute1Dist = 30.0 + rng.Float64()*10.0 // 25マス以上であることを想定
-
-	// 2. 円形航路のロジック (直線が5マス以上続いた場合)
-	// 複雑なため、ここではスキップ
-	fmt.Println("PhaseTransitRoute1: 円形航路ロジックはスキップ")
-}
\ No newline at end of file
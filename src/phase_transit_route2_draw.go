@@ -1,25 +0,0 @@
-// filename: phase_transit_route2_draw.go
-package main
-
-import (
-	"math/rand"
-	"fmt"
-)
-
-// 航路2を描画する
-func (g *Game) PhaseTransitRoute2Draw(w, h int, rng *rand.Rand, gen *World2Generator) {
-	gen.CurrentStep = Phase_CliffsShallows
-	gen.PhaseName = "8. Transit Route 2 (Secondary Draw)"
-	
-	// 大陸の重心 (中心点として仮定)
-	center := struct{ x, y int }{w / 2, h / 2} 
-	_ = center // 未使用変数警告を回避
-	
-	// 1. 孤立島の外接矩形を計算
-	// ... (ロジックはスキップ)
-	
-	// 2. 航路2のロジック（複雑なため、ここではスキップ）
-	fmt.Println("PhaseTransitRoute2Draw: 円形航路2ロジックはスキップ")
-	
-	// 3. 次のフェーズへ進む
-}
\ No newline at end of file
@@ -6,6 +6,8 @@ import (
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
+
+	"myrpg/world2"
 )
 
 func init() {
@@ -80,11 +82,12 @@ func init() {
 	TexW_CityIcon = ebiten.NewImage(24, 24); TexW_CityIcon.Fill(color.RGBA{200, 50, 50, 255})
 
 	fillImg := func(c color.Color) *ebiten.Image { img := ebiten.NewImage(32, 32); img.Fill(c); return img }
-	TexW_Ocean    = fillImg(color.RGBA{20, 60, 120, 255})
-	TexW_Plains   = fillImg(color.RGBA{100, 160, 80, 255})
-	TexW_Forest   = fillImg(color.RGBA{40, 100, 50, 255})
-	TexW_Desert   = fillImg(color.RGBA{200, 180, 100, 255})
-	TexW_Mountain = fillImg(color.RGBA{120, 110, 100, 255})
+	// バイオームの色は World2 のバイオーム表示と共通
+	TexW_Ocean    = fillImg(world2.BiomeColor(world2.BiomeOcean))
+	TexW_Plains   = fillImg(world2.BiomeColor(world2.BiomePlains))
+	TexW_Forest   = fillImg(world2.BiomeColor(world2.BiomeForest))
+	TexW_Desert   = fillImg(world2.BiomeColor(world2.BiomeDesert))
+	TexW_Mountain = fillImg(world2.BiomeColor(world2.BiomeMountain))
 
 	// World 2
 	TexW2_Ocean = ebiten.NewImage(16, 16); TexW2_Ocean.Fill(color.RGBA{20, 60, 150, 255})
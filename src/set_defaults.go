@@ -3,67 +3,52 @@ package main
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
-)
-
-// applyIntSetting は設定マップから整数値を読み込み、gのフィールドに適用する
-func applyIntSetting(settings map[string]string, key string, target *int) {
-	if valStr, ok := settings[key]; ok {
-		if valInt, err := strconv.Atoi(valStr); err == nil {
-			*target = valInt
-		} else {
-			fmt.Printf("Warning: Setting '%s' is not an integer: %v\n", key, err)
-		}
-	}
-}
 
-// applyFloatSetting は設定マップから浮動小数値を読み込み、gのフィールドに適用する
-func applyFloatSetting(settings map[string]string, key string, target *float64) {
-	if valStr, ok := settings[key]; ok {
-		if valFloat, err := strconv.ParseFloat(valStr, 64); err == nil {
-			*target = valFloat
-		} else {
-			fmt.Printf("Warning: Setting '%s' is not a float: %v\n", key, err)
-		}
-	}
-}
-
-// applyBoolSetting は設定マップから真偽値を読み込み、gのフィールドに適用する
-func applyBoolSetting(settings map[string]string, key string, target *bool) {
-	if valStr, ok := settings[key]; ok {
-		valStr = strings.ToLower(valStr)
-		if valStr == "true" || valStr == "on" || valStr == "1" {
-			*target = true
-		} else if valStr == "false" || valStr == "off" || valStr == "0" {
-			*target = false
-		} else {
-			fmt.Printf("Warning: Setting '%s' is not a boolean: %s\n", key, valStr)
-		}
-	}
-}
+	"myrpg/world2"
+)
 
 // ApplySettings は読み込んだ設定値をGame構造体フィールドに適用する
 func (g *Game) ApplySettings(settings map[string]string) {
 	// Int Settings
-	applyIntSetting(settings, "SoilMin", &g.SoilMin)
-	applyIntSetting(settings, "SoilMax", &g.SoilMax)
-	applyIntSetting(settings, "W2Width", &g.W2Width)
-	applyIntSetting(settings, "W2Height", &g.W2Height)
-	applyIntSetting(settings, "TransitDist", &g.TransitDist)
-	applyIntSetting(settings, "VastOceanSize", &g.VastOceanSize)
-	applyIntSetting(settings, "IslandBoundSize", &g.IslandBoundSize)
-	applyIntSetting(settings, "MapRatio", &g.MapRatio)
-	applyIntSetting(settings, "CliffPathLen", &g.CliffPathLen)
-	applyIntSetting(settings, "ForceSwitch", &g.ForceSwitch)
+	world2.ApplyIntSetting(settings, "SoilMin", &g.SoilMin)
+	world2.ApplyIntSetting(settings, "SoilMax", &g.SoilMax)
+	world2.ApplyIntSetting(settings, "W2Width", &g.W2Width)
+	world2.ApplyIntSetting(settings, "W2Height", &g.W2Height)
+	world2.ApplyIntSetting(settings, "TransitDist", &g.TransitDist)
+	world2.ApplyIntSetting(settings, "VastOceanSize", &g.VastOceanSize)
+	world2.ApplyIntSetting(settings, "IslandBoundSize", &g.IslandBoundSize)
+	world2.ApplyIntSetting(settings, "MapRatio", &g.MapRatio)
+	world2.ApplyIntSetting(settings, "MainType", &g.MainType)
+	world2.ApplyIntSetting(settings, "SubType", &g.SubType)
+	world2.ApplyIntSetting(settings, "CliffPathLen", &g.CliffPathLen)
+	world2.ApplyIntSetting(settings, "ForceSwitch", &g.ForceSwitch)
+	world2.ApplyIntSetting(settings, "RiverCount", &g.RiverCount)
+	world2.ApplyIntSetting(settings, "RiverMinLen", &g.RiverMinLen)
+	world2.ApplyIntSetting(settings, "NationCount", &g.NationCount)
+	world2.ApplyIntSetting(settings, "TownCount", &g.TownCount)
+	world2.ApplyInt64Setting(settings, "Seed", &g.W2Seed)
+	world2.ApplyIntSetting(settings, "SeedSearchTries", &g.W2SeedTries)
+
+	// World2 の移動コスト (MoveSoil, MoveRoad, ...)
+	g.W2MoveCosts.ApplySettings(settings)
 
 	// Float Settings
-	applyFloatSetting(settings, "CliffInitVal", &g.CliffInitVal)
-	applyFloatSetting(settings, "CliffDec", &g.CliffDecVal)
-	applyFloatSetting(settings, "ShallowDec", &g.ShallowDecVal)
+	world2.ApplyFloatSetting(settings, "CliffInitVal", &g.CliffInitVal)
+	world2.ApplyFloatSetting(settings, "CliffDec", &g.CliffDecVal)
+	world2.ApplyFloatSetting(settings, "ShallowDec", &g.ShallowDecVal)
 
 	// Bool Settings
-	applyBoolSetting(settings, "Centering", &g.EnableCentering)
+	world2.ApplyBoolSetting(settings, "Centering", &g.EnableCentering)
+	world2.ApplyBoolSetting(settings, "IslandShallow", &g.EnableIslandShallow)
+
+	// String Settings
+	if v, ok := settings["SeedSearch"]; ok {
+		g.W2SeedSearch = v
+	}
+
+	// List Settings
+	world2.ApplyListSetting(settings, "Phases", &g.W2Phases)
+	world2.ApplyListSetting(settings, "DisablePhases", &g.W2DisablePhases)
 	
 	fmt.Println("Settings applied from file. SoilMin:", g.SoilMin)
 }
\ No newline at end of file
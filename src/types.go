@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+
+	"myrpg/world2"
 )
 
 // --- 設定定数 ---
@@ -21,10 +23,10 @@ const (
 	WorldHeight   = 80
 	WorldTileSize = 32
 
-	World2Width         = 150
-	World2Height        = 100
-	DefaultWorld2Width  = 150
-	DefaultWorld2Height = 100
+	World2Width         = world2.DefaultWidth
+	World2Height        = world2.DefaultHeight
+	DefaultWorld2Width  = world2.DefaultWidth
+	DefaultWorld2Height = world2.DefaultHeight
 	World2TileSize      = 16
 )
 
@@ -35,27 +37,6 @@ const (
 	DirWest  = 3
 )
 
-const (
-	W2TileVariableOcean = 0 
-	W2TileSoil          = 1 
-	W2TileFixedOcean    = 2 
-	W2TileTransit       = 3 
-	W2TileCliff         = 4
-	W2TileShallow       = 5
-)
-
-const (
-	SrcNone          = 0
-	SrcMain          = 1
-	SrcSub           = 2
-	SrcMix           = 3
-	SrcBridge        = 4
-	SrcIsland        = 5
-	SrcTransitPath   = 6
-	SrcBRouteIsland  = 7  // B航路の経由島（緑）
-	SrcBRoutePath    = 8  // B航路の航路（暗緑）
-)
-
 // Input Edit Modes
 const (
 	EditNone = 0
@@ -71,28 +52,9 @@ const (
 	EditCliffPath   = 10 
 	EditForceSwitch = 11 
 	EditMapRatio = 14
-)
-
-// World2 Generation Phases (Step ID)
-const (
-	Phase_Init             = 0
-	Phase_MaskGen          = 1
-	Phase_SoilStart        = 2
-	Phase_SoilProgressEnd  = 11
-	Phase_Bridge           = 13
-	Phase_Centering        = 14
-	Phase_IslandsQuad      = 15
-	Phase_IslandsRand      = 16
-	
-	// Transit Phase の拡張
-	Phase_Transit_Start    = 17
-	Phase_IslandShallowAdjust = 18
-	Phase_Transit_Route1   = 19
-	Phase_Transit_Route2_Calc = 20
-	Phase_Transit_Route2_Draw = 21
-
-	Phase_CliffsShallows   = 22
-	Phase_LakesFinal       = 23 
+	EditSeed     = 15
+	EditMainType = 16
+	EditSubType  = 17
 )
 
 var ZoomLevels = []float64{0.7, 0.8, 0.9, 1.0, 1.1, 1.2, 1.3, 1.4, 1.5}
@@ -169,79 +131,32 @@ type WorldMap struct {
 	Zoom             float64
 }
 
-type Rect struct {
-	x, y, w, h int
-}
+// WorldMap2 は world2.WorldMap2 に描画用のカメラ状態を加えたビューア
+// World2 の表示モード
+const (
+	W2RenderSource = 0 // 生成元 (Source) ごとの色
+	W2RenderBiome  = 1 // バイオームごとの色
+	W2RenderNation = 2 // 国ごとの色
 
-type World2Tile struct {
-	Type   int
-	Source int
-	IsLake bool
-}
+	W2RenderModeCount = 3
+)
 
 type WorldMap2 struct {
-	Width, Height    int
-	Tiles            [][]World2Tile
+	*world2.WorldMap2
 	OffsetX, OffsetY float64
 	Zoom             float64
 	ShowGrid         bool
-	StatsInfo        []string
-	PinkRects        []Rect
-}
-
-type GenSnapshot struct {
-	Tiles     [][]World2Tile
-	PhaseName string
-	StepID    int
-	
-	NewSoils         map[int]bool
-	PinkRects        []Rect
-	Walkers          []struct{x, y int}
-	CurrentSoilCount int
-	Multiplier       float64
-	Excluded         map[int]bool
-	CurrentSeed      int64
-	
-	CliffStreak   int
-	ShallowStreak int
-}
-
-type World2Generator struct {
-	CurrentStep int
-	IsFinished  bool
-	PhaseName   string
-	History     []GenSnapshot
-	
-	Rng             *rand.Rand
-	CurrentSeed     int64
-
-	MaskMain        [][]float64
-	MaskSub         [][]float64
-	FinalMask       [][]float64
-	
-	MaskImage       *ebiten.Image 
-
-	Walkers         []struct{ x, y int }
-	TargetSoilCount int
-	CurrentSoilCount int
-	Config          GenConfig
-	
-	NewSoils map[int]bool
-	
-	Multiplier float64
-	Excluded   map[int]bool
-	
-	CliffStreak   int
-	ShallowStreak int
-}
-
-type GenConfig struct {
-	MinPct, MaxPct, W, H, TransitDist, Ratio int
-	VastOcean, IslandBound int
-	Centering bool
-	CliffInit, CliffDec, ShallowDec float64
-	CliffPathLen, ForceSwitch int
-	MainType, SubType int 
+	ShowHillshade    bool // 標高の陰影を重ねて表示する ([E] で切り替え)
+	RenderMode       int  // W2Render* ([V] で切り替え)
+	RouteMode        bool // クリックした2マスの経路を表示する ([T] で切り替え)
+	RoutePoints      []world2.Point // 経路モードでクリックしたマス (始点、終点)
+	RoutePath        []world2.Point // RoutePoints の間の最小コストの経路
+	RouteTurns       int            // RoutePath の移動にかかるターン数
+	PaintMode        bool           // マップをドラッグで塗る手描きモード ([M] で切り替え)
+	Brush            world2.Brush   // 手描きの筆 (種類・生成元・大きさ・形)
+	painting         bool           // 左ボタンを押して塗っている最中
+	lastPaint        world2.Point   // 直前に筆を置いたマス (速いドラッグの間を埋める)
+	MaskImage        *ebiten.Image
 }
 
 type Camera struct {
@@ -266,7 +181,7 @@ type Game struct {
 	Loader *LoadingState
 	World *WorldMap
 	World2 *WorldMap2
-	Gen2 *World2Generator
+	Gen2 *world2.World2Generator
 	Dungeon *Dungeon
 	Party *Party
 	Camera *Camera
@@ -277,8 +192,6 @@ type Game struct {
 	IsDragging bool
 	ArrowTimer float64
 	
-	TotalRoute1Dist float64
-	
 	SoilMin     int
 	SoilMax     int
 	W2Width     int
@@ -288,21 +201,39 @@ type Game struct {
 	IslandBoundSize int
 	
 	MapRatio    int 
+	MainType    int // マスク形状 (1:クラシック 〜 9:勾玉)
+	SubType     int
 	EnableCentering bool
+	EnableIslandShallow bool
+	W2Phases        []string // 段の実行順 (settings の Phases、空なら標準順)
+	W2DisablePhases []string
 	
 	CliffInitVal   float64
 	CliffDecVal    float64
 	ShallowDecVal  float64
 	CliffPathLen   int
 	ForceSwitch    int
+	RiverCount     int // 川の本数 (settings の RiverCount、0 で川なし)
+	RiverMinLen    int
+	NationCount    int // 国の数 (settings の NationCount、0 で国分けなし)
+	TownCount      int // 町と都市の数 (settings の TownCount、0 で集落と道なし)
+	W2MoveCosts    world2.MoveCosts // World2 の移動コスト表 (settings の Move*)
+	W2SeedSearch   string // [F] のシード探索の条件 (settings の SeedSearch、例: islands=3, lakes>=1, largest>60)
+	W2SeedTries    int    // シード探索で試す最大のシード数 (settings の SeedSearchTries)
+
+	W2Seed int64 // 0 の場合は InitWorld2Generator ごとにランダムなシードを使う
 	
-	LastTargetSoil int
 	InputMode int
 	InputBuffer string
 
 	WarningMsg   string
 	WarningTimer float64
 
-	AutoProgress bool // Enterキー押下時に自動で次のフェーズへ進むフラグ
-	SuppressMapDraw bool // 自動進行中はマップ描画を抑制し、Phase名のみ表示
+	W2Job    *world2.BackgroundRun // Enter で始めた裏での生成 (実行中でなければ nil)
+	W2JobSeq int                   // 表示中の途中経過の通し番号
+	W2Search *world2.SeedSearch    // [F] で始めたシード探索 (実行中でなければ nil)
+
+	w2MetricsGen   *world2.World2Generator // w2MetricsLines を計算した生成器と History の長さ
+	w2MetricsSteps int
+	w2MetricsLines []string
 }
\ No newline at end of file
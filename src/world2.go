@@ -2,44 +2,93 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
+	"math"
 	"math/rand"
 	"strconv"
 	"os"
+	"path/filepath"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"golang.org/x/image/font/basicfont"
 	"github.com/hajimehoshi/ebiten/v2/text"
+
+	"myrpg/world2"
 )
 
 // InitWorld2Generator は main.go/menu.go から参照されるため、ここに残す
 func (g *Game) InitWorld2Generator() {
+	g.dropWorld2Generation()
+	g.dropWorld2SeedSearch()
+
+	// シード初期化 (W2Seed 指定時は同じマップを再現する)
+	startSeed := g.W2Seed
+	if startSeed == 0 {
+		startSeed = rand.Int63()
+	}
+
 	// Validation
-	if g.W2Width < 40 || g.W2Height < 40 {
-		g.WarningMsg = "Size >= 40x40"
+	gen, err := world2.NewGenerator(g.World2Config(), startSeed)
+	if err != nil {
+		g.WarningMsg = err.Error()
 		g.WarningTimer = 3.0
 		return
 	}
-	if g.W2Width > 500 || g.W2Height > 500 {
-		g.WarningMsg = "Size <= 500x500"
-		g.WarningTimer = 3.0
-		return
+	g.attachWorld2Generator(gen)
+}
+
+// World2Config は UI の入力値から GenConfig を組み立てる
+func (g *Game) World2Config() world2.GenConfig {
+	return world2.GenConfig{
+		MinPct: g.SoilMin, MaxPct: g.SoilMax, W: g.W2Width, H: g.W2Height,
+		TransitDist: g.TransitDist,
+		VastOcean: g.VastOceanSize, IslandBound: g.IslandBoundSize,
+		MainType: g.MainType, SubType: g.SubType, Ratio: g.MapRatio, 
+		Centering: g.EnableCentering,
+		IslandShallow: g.EnableIslandShallow,
+		CliffInit: g.CliffInitVal, CliffDec: g.CliffDecVal, ShallowDec: g.ShallowDecVal,
+		CliffPathLen: g.CliffPathLen,
+		ForceSwitch: g.ForceSwitch,
+		RiverCount: g.RiverCount, RiverMinLen: g.RiverMinLen,
+		NationCount: g.NationCount, TownCount: g.TownCount,
+		Phases: g.W2Phases, DisablePhases: g.W2DisablePhases,
 	}
+}
+
+// applyWorld2Config は読み込んだマップの GenConfig を UI の入力値に反映する
+func (g *Game) applyWorld2Config(cfg world2.GenConfig) {
+	g.SoilMin, g.SoilMax = cfg.MinPct, cfg.MaxPct
+	g.W2Width, g.W2Height = cfg.W, cfg.H
+	g.TransitDist = cfg.TransitDist
+	g.VastOceanSize, g.IslandBoundSize = cfg.VastOcean, cfg.IslandBound
+	g.MapRatio = cfg.Ratio
+	g.MainType, g.SubType = cfg.MainType, cfg.SubType
+	g.EnableCentering = cfg.Centering
+	g.EnableIslandShallow = cfg.IslandShallow
+	g.CliffInitVal, g.CliffDecVal, g.ShallowDecVal = cfg.CliffInit, cfg.CliffDec, cfg.ShallowDec
+	g.CliffPathLen = cfg.CliffPathLen
+	g.ForceSwitch = cfg.ForceSwitch
+	g.RiverCount, g.RiverMinLen = cfg.RiverCount, cfg.RiverMinLen
+	g.NationCount, g.TownCount = cfg.NationCount, cfg.TownCount
+	g.W2Phases, g.W2DisablePhases = cfg.Phases, cfg.DisablePhases
+}
 
+// attachWorld2Generator は生成器を表示対象にし、マップ全体が収まるようにカメラを合わせる
+func (g *Game) attachWorld2Generator(gen *world2.World2Generator) {
+	w, h := gen.World2.Width, gen.World2.Height
+	g.Gen2 = gen
 	g.World2 = &WorldMap2{
-		Width:   g.W2Width,
-		Height:  g.W2Height,
-		Tiles:   make([][]World2Tile, g.W2Width),
-		OffsetX: float64(g.W2Width*World2TileSize / 2),
-		OffsetY: float64(g.W2Height*World2TileSize / 2),
-		PinkRects: []Rect{},
+		WorldMap2: gen.World2,
+		OffsetX: float64(w*World2TileSize / 2),
+		OffsetY: float64(h*World2TileSize / 2),
 	}
 
-	mapPixelW := float64(g.W2Width * World2TileSize)
-	mapPixelH := float64(g.W2Height * World2TileSize)
+	mapPixelW := float64(w * World2TileSize)
+	mapPixelH := float64(h * World2TileSize)
 	scaleW := float64(ScreenWidth) / mapPixelW
 	scaleH := float64(ScreenHeight) / mapPixelH
 	if scaleW < scaleH {
@@ -49,166 +98,365 @@ func (g *Game) InitWorld2Generator() {
 	}
 	g.World2.Zoom *= 0.9
 
-	// シード初期化
-	startSeed := rand.Int63()
-
-	gen := &World2Generator{
-		CurrentStep: 0,
-		IsFinished:  false,
-		PhaseName:   "0. Init (Fixed Ocean)",
-		History:     []GenSnapshot{},
-		Rng:         rand.New(rand.NewSource(startSeed)),
-		CurrentSeed: startSeed,
-		Config: GenConfig{
-			MinPct: g.SoilMin, MaxPct: g.SoilMax, W: g.W2Width, H: g.W2Height,
-			TransitDist: g.TransitDist,
-			VastOcean: g.VastOceanSize, IslandBound: g.IslandBoundSize,
-			// MapTypeMain, MapTypeSub は固定値 1
-			MainType: 1, SubType: 1, Ratio: g.MapRatio, 
-			Centering: g.EnableCentering,
-			CliffInit: g.CliffInitVal, CliffDec: g.CliffDecVal, ShallowDec: g.ShallowDecVal,
-			CliffPathLen: g.CliffPathLen,
-			ForceSwitch: g.ForceSwitch,
-		},
-		Multiplier: g.CliffInitVal,
-		Excluded:   make(map[int]bool),
-		NewSoils:   make(map[int]bool),
-	}
-
-	for x := 0; x < g.W2Width; x++ {
-		g.World2.Tiles[x] = make([]World2Tile, g.W2Height)
-		for y := 0; y < g.W2Height; y++ {
-			if x < 3 || x >= g.W2Width-3 || y < 3 || y >= g.W2Height-3 {
-				g.World2.Tiles[x][y] = World2Tile{Type: W2TileFixedOcean}
-			} else {
-				g.World2.Tiles[x][y] = World2Tile{Type: W2TileVariableOcean}
-			}
+	g.World2.UpdateMaskImage(g.Gen2.FinalMask)
+}
+
+// SaveWorld2 は現在のマップを JSON とバイナリの両形式で保存する
+func (g *Game) SaveWorld2() {
+	data := g.Gen2.SaveData()
+	for _, path := range []string{World2SaveJSON, World2SaveBinary} {
+		if err := world2.SaveFile(path, data); err != nil {
+			g.WarningMsg = fmt.Sprintf("Save failed: %v", err)
+			g.WarningTimer = 3.0
+			return
 		}
 	}
+	g.WarningMsg = "Saved: " + World2SaveJSON + ", " + World2SaveBinary
+	g.WarningTimer = 2.0
+}
 
-	g.Gen2 = gen
-	g.TotalRoute1Dist = 0.0 // 初期化
-	g.Gen2.UpdateMaskImage(g.W2Width, g.W2Height)
-	g.SaveSnapshot()
+// ExportWorld2PNG は現在のマップを PNG に書き出す
+func (g *Game) ExportWorld2PNG() {
+	path := fmt.Sprintf("world2_%d.png", g.Gen2.StartSeed)
+	if err := g.World2.SavePNG(path, World2ExportScale); err != nil {
+		g.WarningMsg = fmt.Sprintf("Export failed: %v", err)
+		g.WarningTimer = 3.0
+		return
+	}
+	g.WarningMsg = "Exported: " + path
+	g.WarningTimer = 2.0
 }
 
-func (g *Game) SaveSnapshot() {
-	w, h := g.Gen2.Config.W, g.Gen2.Config.H
-	tilesCopy := make([][]World2Tile, w)
-	for x := 0; x < w; x++ {
-		tilesCopy[x] = make([]World2Tile, h)
-		copy(tilesCopy[x], g.World2.Tiles[x])
+// ExportWorld2History は History の全スナップショットを連番 PNG とコンタクトシートに書き出す
+func (g *Game) ExportWorld2History() {
+	dir := fmt.Sprintf("world2_%d_history", g.Gen2.StartSeed)
+	sheet := filepath.Join(dir, "sheet.png")
+	_, err := g.Gen2.ExportHistoryPNGs(dir, World2ExportScale)
+	if err == nil {
+		err = g.Gen2.SaveContactSheet(sheet, 5, 2)
 	}
-	
-	newSoilsCopy := make(map[int]bool)
-	for k, v := range g.Gen2.NewSoils { newSoilsCopy[k] = v }
-	
-	exCopy := make(map[int]bool)
-	for k, v := range g.Gen2.Excluded { exCopy[k] = v }
-
-	pinkCopy := make([]Rect, len(g.World2.PinkRects))
-	copy(pinkCopy, g.World2.PinkRects)
-
-	walkersCopy := make([]struct{x, y int}, len(g.Gen2.Walkers))
-	copy(walkersCopy, g.Gen2.Walkers)
-
-	g.Gen2.History = append(g.Gen2.History, GenSnapshot{
-		Tiles:     tilesCopy,
-		PhaseName: g.Gen2.PhaseName,
-		StepID:    g.Gen2.CurrentStep,
-		NewSoils:  newSoilsCopy,
-		Excluded:  exCopy,
-		Multiplier: g.Gen2.Multiplier,
-		PinkRects: pinkCopy,
-		Walkers:   walkersCopy,
-		CurrentSoilCount: g.Gen2.CurrentSoilCount,
-		CurrentSeed: g.Gen2.CurrentSeed,
-		CliffStreak: g.Gen2.CliffStreak,
-		ShallowStreak: g.Gen2.ShallowStreak,
-	})
+	if err != nil {
+		g.WarningMsg = fmt.Sprintf("Export failed: %v", err)
+		g.WarningTimer = 3.0
+		return
+	}
+	g.WarningMsg = "Exported: " + sheet
+	g.WarningTimer = 2.0
 }
 
+// LoadWorld2 は保存済みマップを再生成せずに読み込む (バイナリ優先、無ければ JSON)
+func (g *Game) LoadWorld2() {
+	data, err := world2.LoadFile(World2SaveBinary)
+	if os.IsNotExist(err) {
+		data, err = world2.LoadFile(World2SaveJSON)
+	}
+	var gen *world2.World2Generator
+	if err == nil {
+		gen, err = world2.NewGeneratorFromSave(data)
+	}
+	if err != nil {
+		g.WarningMsg = fmt.Sprintf("Load failed: %v", err)
+		g.WarningTimer = 3.0
+		return
+	}
+	g.dropWorld2Generation()
+	g.dropWorld2SeedSearch()
+	g.applyWorld2Config(gen.Config)
+	g.attachWorld2Generator(gen)
+}
+
+// NextStep は生成器を1フェーズ進め、マスク画像を更新する
+func (g *Game) NextStep() {
+	g.Gen2.NextStep()
+	g.World2.UpdateMaskImage(g.Gen2.FinalMask)
+}
+
+// UndoStep は生成器を1フェーズ戻し、マスク画像を更新する
 func (g *Game) UndoStep() {
-	if len(g.Gen2.History) > 1 {
-		g.Gen2.History = g.Gen2.History[:len(g.Gen2.History)-1]
-		last := g.Gen2.History[len(g.Gen2.History)-1]
-		
-		for x := 0; x < g.Gen2.Config.W; x++ {
-			copy(g.World2.Tiles[x], last.Tiles[x])
+	g.Gen2.UndoStep()
+	g.World2.UpdateMaskImage(g.Gen2.FinalMask)
+}
+
+// JumpWorld2 は History/Redo を通した i 番目のスナップショットに移り、マスク画像を更新する
+func (g *Game) JumpWorld2(i int) {
+	g.Gen2.JumpTo(i)
+	g.World2.UpdateMaskImage(g.Gen2.FinalMask)
+}
+
+// BranchWorld2 は現在の段から先を新しいシードで最後まで生成し直す
+// 完成したマップでは分岐する段がないので、戻ってから分岐するよう警告だけ出す
+func (g *Game) BranchWorld2() {
+	if g.W2Job != nil {
+		return
+	}
+	seed := rand.Int63()
+	if !g.Gen2.Branch(seed) {
+		g.WarningMsg = "Branch: map is finished, go back a step first ([PgUp] / timeline)"
+		g.WarningTimer = 2.0
+		return
+	}
+	g.StartWorld2Generation()
+	g.WarningMsg = fmt.Sprintf("Branch from %s (Seed %d)", g.Gen2.PhaseName, seed)
+	g.WarningTimer = 2.0
+}
+
+// StartWorld2Generation は現在の生成器を裏の goroutine で最後の段まで進める
+// 実行中は途中の複製を表示し、終わったら生成器を表示に戻す (pollWorld2Generation)
+func (g *Game) StartWorld2Generation() {
+	if g.W2Job != nil || g.Gen2.IsFinished {
+		return
+	}
+	g.W2Job = g.Gen2.RunInBackground(World2PreviewInterval)
+	preview, seq := g.W2Job.Preview()
+	g.W2JobSeq = seq
+	g.showWorld2(preview)
+}
+
+// CancelWorld2Generation は裏の生成を止める。実行中の段が終わると、そこまでの生成器が表示に戻る
+func (g *Game) CancelWorld2Generation() {
+	if g.W2Job != nil {
+		g.W2Job.Cancel()
+	}
+}
+
+// dropWorld2Generation は裏の生成を止めて結果を捨てる (生成器を作り直す・読み込む前に呼ぶ)
+func (g *Game) dropWorld2Generation() {
+	if g.W2Job != nil {
+		g.W2Job.Cancel()
+		g.W2Job = nil
+	}
+}
+
+// pollWorld2Generation は毎フレーム、途中経過の表示を更新し、生成が終わっていれば生成器を表示に戻す
+func (g *Game) pollWorld2Generation() {
+	if g.W2Job == nil {
+		return
+	}
+	if g.W2Job.Done() {
+		gen := g.W2Job.Wait()
+		if g.W2Job.Canceled() {
+			g.WarningMsg = "Canceled after " + gen.PhaseName
+			g.WarningTimer = 2.0
 		}
-		
-		g.Gen2.PhaseName = last.PhaseName
-		g.Gen2.CurrentStep = last.StepID
-		g.Gen2.IsFinished = false
+		g.W2Job = nil
+		g.showWorld2(gen)
+		return
+	}
+	if preview, seq := g.W2Job.Preview(); seq != g.W2JobSeq {
+		g.W2JobSeq = seq
+		g.showWorld2(preview)
+	}
+}
 
-		g.Gen2.NewSoils = make(map[int]bool)
-		for k, v := range last.NewSoils { g.Gen2.NewSoils[k] = v }
-		
-		g.Gen2.Excluded = make(map[int]bool)
-		for k, v := range last.Excluded { g.Gen2.Excluded[k] = v }
-		
-		g.World2.PinkRects = make([]Rect, len(last.PinkRects))
-		copy(g.World2.PinkRects, last.PinkRects)
+// showWorld2 は表示する生成器を差し替える (カメラ・表示モード・経路はそのまま)
+func (g *Game) showWorld2(gen *world2.World2Generator) {
+	g.Gen2 = gen
+	g.World2.WorldMap2 = gen.World2
+	g.World2.UpdateMaskImage(gen.FinalMask)
+}
 
-		g.Gen2.Walkers = make([]struct{x, y int}, len(last.Walkers))
-		copy(g.Gen2.Walkers, last.Walkers)
-		
-		g.Gen2.CurrentSoilCount = last.CurrentSoilCount
-		g.Gen2.Multiplier = last.Multiplier
-		g.Gen2.CurrentSeed = last.CurrentSeed
-		g.Gen2.Rng.Seed(g.Gen2.CurrentSeed)
-		g.Gen2.CliffStreak = last.CliffStreak
-		g.Gen2.ShallowStreak = last.ShallowStreak
+// ToggleWorld2SeedSearch は settings の SeedSearch の条件を満たすシードを裏で探し始める ([F])
+// 探索中に呼ぶと探索を止める
+func (g *Game) ToggleWorld2SeedSearch() {
+	if g.W2Search != nil {
+		g.W2Search.Cancel()
+		return
+	}
+	cs, err := world2.ParseConstraints(g.W2SeedSearch)
+	if err == nil {
+		err = g.World2Config().Validate()
+	}
+	if err == nil && g.W2SeedTries < 1 {
+		err = errors.New("SeedSearchTries must be >= 1")
+	}
+	if err != nil {
+		g.WarningMsg = err.Error()
+		g.WarningTimer = 3.0
+		return
 	}
+	start := g.W2Seed
+	if start == 0 {
+		// SearchSeed は start から tries 個のシードを試すので、最後のシードが溢れない範囲から選ぶ
+		start = rand.Int63n(math.MaxInt64 - int64(g.W2SeedTries))
+	}
+	g.W2Search = world2.StartSeedSearch(g.World2Config(), cs, start, g.W2SeedTries)
 }
 
-// NextStep のラッパー
-func (g *Game) NextStep() {
-	if g.Gen2.IsFinished { return }
-	
-	gen := g.Gen2
-	w, h := gen.Config.W, gen.Config.H
-	
-	gen.Rng.Seed(gen.CurrentSeed)
-	rng := gen.Rng
-
-	gen.NewSoils = make(map[int]bool)
-	g.World2.PinkRects = []Rect{} 
-
-	switch gen.CurrentStep {
-	case Phase_Init:
-		g.PhaseInit(w, h, rng, gen)
-	case Phase_MaskGen:
-		g.PhaseMaskGen(w, h, rng, gen)
-	case Phase_SoilStart, 3, 4, 5, 6, 7, 8, 9, 10, Phase_SoilProgressEnd:
-		g.PhaseSoilProgress(w, h, rng, gen)
-	case Phase_Bridge:
-		g.PhaseBridge(w, h, rng, gen)
-	case Phase_Centering:
-		g.PhaseCentering(w, h, rng, gen)
-	case Phase_IslandsQuad:
-		g.PhaseIslandsQuad(w, h, rng, gen)
-	case Phase_IslandsRand:
-		g.PhaseIslandsRand(w, h, rng, gen)
-	case Phase_Transit_Start:
-		g.PhaseTransitStart(w, h, rng, gen)
-	case Phase_IslandShallowAdjust:
-		g.PhaseIslandShallowAdjust(w, h, rng, gen)
-	case Phase_Transit_Route1:
-		g.PhaseTransitRoute1(w, h, rng, gen)
-	case Phase_Transit_Route2_Calc:
-		g.PhaseTransitRoute2Calc(w, h, rng, gen)
-	case Phase_Transit_Route2_Draw:
-		g.PhaseTransitRoute2Draw(w, h, rng, gen)
-	case Phase_CliffsShallows:
-		g.PhaseCliffsShallows(w, h, rng, gen)
-	case Phase_LakesFinal:
-		g.PhaseLakesFinal(w, h, rng, gen)
+// dropWorld2SeedSearch はシード探索を止めて結果を捨てる (表示中のマップを作り直す・読み込む・描き換える前に呼ぶ)
+// 捨てないと、探索が終わった時に見つかったマップで置き換えてしまう
+func (g *Game) dropWorld2SeedSearch() {
+	if g.W2Search != nil {
+		g.W2Search.Cancel()
+		g.W2Search = nil
+		g.WarningMsg = "Seed search canceled"
+		g.WarningTimer = 2.0
+	}
+}
+
+// pollWorld2SeedSearch は毎フレーム、シード探索が終わっていれば見つかったマップを表示する
+func (g *Game) pollWorld2SeedSearch() {
+	if g.W2Search == nil || !g.W2Search.Done() {
+		return
+	}
+	gen, err := g.W2Search.Result()
+	tried, _ := g.W2Search.Tried()
+	g.W2Search = nil
+	if err != nil {
+		g.WarningMsg = fmt.Sprintf("Seed search: %v (%d seeds)", err, tried)
+		g.WarningTimer = 3.0
+		return
+	}
+	g.dropWorld2Generation()
+	g.attachWorld2Generator(gen)
+	g.WarningMsg = fmt.Sprintf("Found seed %d after %d seeds", gen.StartSeed, tried)
+	g.WarningTimer = 2.0
+}
+
+// world2MetricsLines は完成したマップの指標の要約を返す (生成器か History が変わった時だけ計算し直す)
+func (g *Game) world2MetricsLines() []string {
+	if !g.Gen2.IsFinished {
+		return nil
+	}
+	if g.w2MetricsGen != g.Gen2 || g.w2MetricsSteps != len(g.Gen2.History) {
+		g.w2MetricsGen, g.w2MetricsSteps = g.Gen2, len(g.Gen2.History)
+		g.w2MetricsLines = g.World2.Metrics().Lines()
+	}
+	return g.w2MetricsLines
+}
+
+// PickWorld2Route は経路モードで画面座標 (mx, my) のマスを始点か終点にし、2点そろったら経路を探す
+func (g *Game) PickWorld2Route(mx, my int) {
+	p, ok := g.world2TileAt(mx, my)
+	if !ok {
+		return
+	}
+	if len(g.World2.RoutePoints) >= 2 {
+		g.World2.RoutePoints, g.World2.RoutePath = nil, nil
+	}
+	g.World2.RoutePoints = append(g.World2.RoutePoints, p)
+	if len(g.World2.RoutePoints) < 2 {
+		return
+	}
+	path, turns, err := g.World2.FindPath(g.World2.RoutePoints[0], g.World2.RoutePoints[1], g.W2MoveCosts)
+	if err != nil {
+		g.WarningMsg = fmt.Sprintf("Route: %v", err)
+		g.WarningTimer = 2.0
+		return
+	}
+	g.World2.RoutePath, g.World2.RouteTurns = path, turns
+}
+
+// world2TileAt は画面座標 (mx, my) にあるマップのマスを返す (マップの外なら偽)
+func (g *Game) world2TileAt(mx, my int) (world2.Point, bool) {
+	fx := ((float64(mx)-ScreenWidth/2)/g.World2.Zoom + g.World2.OffsetX) / float64(World2TileSize)
+	fy := ((float64(my)-ScreenHeight/2)/g.World2.Zoom + g.World2.OffsetY) / float64(World2TileSize)
+	if fx < 0 || fy < 0 || int(fx) >= g.World2.Width || int(fy) >= g.World2.Height {
+		return world2.Point{}, false
+	}
+	return world2.Point{X: int(fx), Y: int(fy)}, true
+}
+
+// updateWorld2Paint は手描きモードの筆の切り替えと、マップのドラッグでの描画を行う
+// 左ボタンを離した時に、1回のドラッグで塗った分を1つの段として History に記録する (PgUp で戻せる)
+func (g *Game) updateWorld2Paint() {
+	v := g.World2
+	b := &v.Brush
+	// 1-6: 塗る種類 (W2Tile* の順) / O: 生成元 / Tab: 形 / -, =, ホイール: 大きさ / K: 湖の判定をやり直す
+	for k := ebiten.Key1; k <= ebiten.Key6; k++ {
+		if inpututil.IsKeyJustPressed(k) {
+			b.Type = int(k - ebiten.Key1)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+		b.Source = (b.Source + 1) % (world2.SrcRoute2Path + 1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		b.Shape = (b.Shape + 1) % world2.BrushShapeCount
+	}
+	_, wheel := ebiten.Wheel()
+	if ebiten.IsKeyPressed(ebiten.KeyControl) {
+		wheel = 0 // Ctrl+ホイールはズーム
+	}
+	if (inpututil.IsKeyJustPressed(ebiten.KeyMinus) || wheel < 0) && b.Size > 0 {
+		b.Size--
+	}
+	if (inpututil.IsKeyJustPressed(ebiten.KeyEqual) || wheel > 0) && b.Size < 20 {
+		b.Size++
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) {
+		g.dropWorld2SeedSearch()
+		n := g.Gen2.RecomputeLakes()
+		g.WarningMsg = fmt.Sprintf("Lakes recomputed: %d tiles changed", n)
+		g.WarningTimer = 2.0
+	}
+
+	mx, my := ebiten.CursorPosition()
+	p, onMap := g.world2TileAt(mx, my)
+	switch {
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft):
+		if onMap && mx > 210 && !ebiten.IsKeyPressed(ebiten.KeyControl) && g.world2TimelineRow(mx, my) < 0 {
+			g.dropWorld2SeedSearch()
+			v.painting, v.lastPaint = true, p
+			g.Gen2.Paint(p.X, p.Y, *b)
+		}
+	case v.painting && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft):
+		// 塗りつぶしは押した時の1回だけ。それ以外は直前のマスから線でつないで塗る
+		if onMap && b.Shape != world2.BrushFill && p != v.lastPaint {
+			dx, dy := p.X-v.lastPaint.X, p.Y-v.lastPaint.Y
+			steps := max(dx, -dx, dy, -dy)
+			for i := 1; i <= steps; i++ {
+				g.Gen2.Paint(v.lastPaint.X+dx*i/steps, v.lastPaint.Y+dy*i/steps, *b)
+			}
+			v.lastPaint = p
+		}
+	}
+	if v.painting && !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		v.painting = false
+		g.Gen2.CommitEdit("Edit: " + world2.TileTypeNames[b.Type])
+	}
+}
+
+// world2TimelineRow は画面座標 (mx, my) にあるタイムラインの行番号を返す (行がなければ -1)
+func (g *Game) world2TimelineRow(mx, my int) int {
+	if mx < World2TimelineX || mx >= World2TimelineX+World2TimelineW || my < World2TimelineY {
+		return -1
+	}
+	i := (my - World2TimelineY) / World2TimelineRowH
+	if i >= g.Gen2.TimelineLen() {
+		return -1
 	}
+	return i
+}
+
+// UpdateMaskImage は FinalMask を Ebiten Image に変換する
+func (m *WorldMap2) UpdateMaskImage(mask [][]float64) {
+	w, h := m.Width, m.Height
+	if m.MaskImage == nil {
+		m.MaskImage = ebiten.NewImage(w, h)
+	}
+	m.MaskImage.Clear()
 	
-	gen.CurrentStep++ // 各フェーズの処理メソッド内で次のフェーズに移行するロジックを削除したため、ここでインクリメント
-	gen.CurrentSeed = gen.Rng.Int63() // Save next seed
-	g.SaveSnapshot()
+	pix := make([]byte, w*h*4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			idx := (y*w + x) * 4
+			if mask != nil {
+				val := mask[x][y]
+				if val > 0 {
+					gray := uint8(val * 255)
+					pix[idx] = gray
+					pix[idx+1] = gray
+					pix[idx+2] = gray
+					pix[idx+3] = 100
+				} else {
+					pix[idx+3] = 0
+				}
+			} else {
+				pix[idx+3] = 0
+			}
+		}
+	}
+	m.MaskImage.WritePixels(pix)
 }
 
 
@@ -220,7 +468,7 @@ func (g *Game) UpdateWorld2() error {
 
 	// F1キー: 設定ファイル読み込みとリセット
 	if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
-		if settings, err := LoadSettings(SettingsFilename); err == nil {
+		if settings, err := world2.LoadSettings(SettingsFilename); err == nil {
 			g.ApplySettings(settings)
 			g.InitWorld2Generator() // 設定適用後、生成をリセット
 		} else if !os.IsNotExist(err) {
@@ -229,51 +477,109 @@ func (g *Game) UpdateWorld2() error {
 		}
 	}
 
+	// S: 保存 / L: 読み込み / P, H: 画像出力 (UI編集モード以外。保存と History の出力は裏で生成中は不可)
+	if g.InputMode == EditNone {
+		if inpututil.IsKeyJustPressed(ebiten.KeyS) && g.W2Job == nil {
+			g.SaveWorld2()
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+			g.LoadWorld2()
+			return nil
+		}
+		// P: マップPNG / H: 全ステップの連番PNGとコンタクトシート
+		if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+			g.ExportWorld2PNG()
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyH) && g.W2Job == nil {
+			g.ExportWorld2History()
+		}
+	}
+
 	// ** Rキーは最優先でリセット **
 	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
 		g.InitWorld2Generator() // Reset
 		return nil
 	}
 
-	// Enter キーの処理 (UI編集モード以外)
+	// Enter キーの処理 (UI編集モード以外): 裏で最後まで生成する。生成中なら止める
 	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && g.InputMode == EditNone {
-
-		// Phase 10 (IsFinished) の場合はリセットして自動進行開始
-		if g.Gen2.IsFinished {
-			g.InitWorld2Generator()
-			g.AutoProgress = true
-			g.SuppressMapDraw = true
+		if g.W2Job != nil {
+			g.CancelWorld2Generation()
 		} else {
-			// 自動進行モードを開始
-			g.AutoProgress = true
-			g.SuppressMapDraw = true
+			// 生成済みの場合はリセットしてから生成する
+			if g.Gen2.IsFinished {
+				g.InitWorld2Generator()
+			}
+			g.StartWorld2Generation()
 		}
 	}
 
-	// 自動進行モード: 1フレームごとに1フェーズ進める
-	if g.AutoProgress && !g.Gen2.IsFinished {
-		g.NextStep()
-	}
+	// 裏での生成: 途中経過の表示を更新し、終わったら生成器を表示に戻す
+	g.pollWorld2Generation()
 
-	// 生成完了時に自動進行を停止し、マップ描画を再開
-	if g.Gen2.IsFinished && g.AutoProgress {
-		g.AutoProgress = false
-		g.SuppressMapDraw = false
+	// F: 条件を満たすシードを裏で探す (探索中は止める)
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) && g.InputMode == EditNone {
+		g.ToggleWorld2SeedSearch()
 	}
+	g.pollWorld2SeedSearch()
 
-	// PgDn/PgUp (UI編集モード以外)
-	if g.InputMode == EditNone {
+	// PgDn/PgUp (UI編集モード以外、裏で生成中は不可)
+	if g.InputMode == EditNone && g.W2Job == nil {
 		if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
 			g.NextStep()
 		}
 		if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
 			g.UndoStep()
 		}
+		// B: 現在の段から新しいシードで分岐して生成し直す
+		if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+			g.BranchWorld2()
+		}
 	}
 
 	if inpututil.IsKeyJustPressed(ebiten.KeyF12) {
 		g.World2.ShowGrid = !g.World2.ShowGrid
 	}
+	// V: 表示モード (生成元 / バイオーム / 国) の切り替え
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) && g.InputMode == EditNone {
+		g.World2.RenderMode = (g.World2.RenderMode + 1) % W2RenderModeCount
+	}
+	// E: 標高の陰影 (Hillshade) 表示の切り替え
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) && g.InputMode == EditNone {
+		g.World2.ShowHillshade = !g.World2.ShowHillshade
+	}
+	// T: 経路モードの切り替え (切り替えるたびに経路は消す)
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) && g.InputMode == EditNone {
+		g.World2.RouteMode = !g.World2.RouteMode
+		g.World2.RoutePoints, g.World2.RoutePath = nil, nil
+	}
+
+	// M: 手描きモードの切り替え (裏で生成中は不可。塗っている最中なら、そこまでを記録する)
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) && g.InputMode == EditNone && g.W2Job == nil {
+		g.World2.PaintMode = !g.World2.PaintMode
+		if g.World2.painting {
+			g.World2.painting = false
+			g.Gen2.CommitEdit("Edit: " + world2.TileTypeNames[g.World2.Brush.Type])
+		}
+	}
+	if g.World2.PaintMode && g.InputMode == EditNone && g.W2Job == nil {
+		g.updateWorld2Paint()
+	}
+
+	// --- タイムライン (右側) のクリックでその段へ移動 ---
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && g.InputMode == EditNone {
+		if row := g.world2TimelineRow(ebiten.CursorPosition()); row >= 0 {
+			if g.W2Job == nil {
+				g.JumpWorld2(row)
+			}
+			return nil
+		}
+		// 経路モード: 入力パネルより右のマップをクリックしたら始点・終点にする (Ctrl はドラッグ用)
+		if mx, my := ebiten.CursorPosition(); g.World2.RouteMode && !g.World2.PaintMode && mx > 210 && !ebiten.IsKeyPressed(ebiten.KeyControl) {
+			g.PickWorld2Route(mx, my)
+			return nil
+		}
+	}
 
 	// --- UI入力モードの開始 (マウス) ---
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
@@ -299,7 +605,12 @@ func (g *Game) UpdateWorld2() error {
 				newMode = EditMapRatio
 				g.InputBuffer = fmt.Sprintf("%d", g.MapRatio)
 			} else if my >= 340 && my <= 370 {
-				g.EnableCentering = !g.EnableCentering // Centering はトグルなのでここで処理を完結
+				// この行は左右2つのトグル (Centering / Island Shallow) なので、ここで処理を完結
+				if mx < 110 {
+					g.EnableCentering = !g.EnableCentering
+				} else {
+					g.EnableIslandShallow = !g.EnableIslandShallow
+				}
 			} else if my >= 380 && my <= 410 {
 				newMode = EditCliffInit
 				g.InputBuffer = fmt.Sprintf("%.1f", g.CliffInitVal)
@@ -315,6 +626,15 @@ func (g *Game) UpdateWorld2() error {
 			} else if my >= 540 && my <= 570 {
 				newMode = EditForceSwitch
 				g.InputBuffer = fmt.Sprintf("%d", g.ForceSwitch)
+			} else if my >= 580 && my <= 610 {
+				newMode = EditSeed
+				g.InputBuffer = fmt.Sprintf("%d", g.Gen2.StartSeed)
+			} else if my >= 620 && my <= 650 {
+				newMode = EditMainType
+				g.InputBuffer = fmt.Sprintf("%d", g.MainType)
+			} else if my >= 660 && my <= 690 {
+				newMode = EditSubType
+				g.InputBuffer = fmt.Sprintf("%d", g.SubType)
 			}
 		}
 		
@@ -362,6 +682,20 @@ func (g *Game) UpdateWorld2() error {
 					}
 				case EditForceSwitch:
 					g.ForceSwitch = valInt
+				case EditMainType:
+					if valInt >= world2.MinMaskType && valInt <= world2.MaxMaskType {
+						g.MainType = valInt
+					}
+				case EditSubType:
+					if valInt >= world2.MinMaskType && valInt <= world2.MaxMaskType {
+						g.SubType = valInt
+					}
+				}
+			}
+			if g.InputMode == EditSeed {
+				// 0 を入力するとランダムシードに戻る
+				if valSeed, err := strconv.ParseInt(g.InputBuffer, 10, 64); err == nil && valSeed >= 0 {
+					g.W2Seed = valSeed
 				}
 			}
 			if errFloat == nil {
@@ -455,130 +789,184 @@ func (g *Game) DrawWorld2(screen *ebiten.Image) {
 	w := g.World2.Width
 	h := g.World2.Height
 
-	// 自動進行中はマップ描画をスキップ
-	if !g.SuppressMapDraw {
-		startX := int(g.World2.OffsetX/float64(World2TileSize)) - int(float64(ScreenWidth)/g.World2.Zoom/float64(World2TileSize))/2 - 2
-		startY := int(g.World2.OffsetY/float64(World2TileSize)) - int(float64(ScreenHeight)/g.World2.Zoom/float64(World2TileSize))/2 - 2
-		endX := startX + int(float64(ScreenWidth)/g.World2.Zoom/float64(World2TileSize)) + 4
-		endY := startY + int(float64(ScreenHeight)/g.World2.Zoom/float64(World2TileSize)) + 4
+	startX := int(g.World2.OffsetX/float64(World2TileSize)) - int(float64(ScreenWidth)/g.World2.Zoom/float64(World2TileSize))/2 - 2
+	startY := int(g.World2.OffsetY/float64(World2TileSize)) - int(float64(ScreenHeight)/g.World2.Zoom/float64(World2TileSize))/2 - 2
+	endX := startX + int(float64(ScreenWidth)/g.World2.Zoom/float64(World2TileSize)) + 4
+	endY := startY + int(float64(ScreenHeight)/g.World2.Zoom/float64(World2TileSize)) + 4
 
-		for x := startX; x <= endX; x++ {
-		for y := startY; y <= endY; y++ {
-			if x < 0 || x >= w || y < 0 || y >= h {
-				continue
-			}
-			tile := g.World2.Tiles[x][y]
+	for x := startX; x <= endX; x++ {
+	for y := startY; y <= endY; y++ {
+		if x < 0 || x >= w || y < 0 || y >= h {
+			continue
+		}
+		tile := g.World2.Tiles[x][y]
 
-			sx := (float64(x)*float64(World2TileSize) - g.World2.OffsetX) * g.World2.Zoom + ScreenWidth/2
-			sy := (float64(y)*float64(World2TileSize) - g.World2.OffsetY) * g.World2.Zoom + ScreenHeight/2
-			size := float64(World2TileSize) * g.World2.Zoom
+		sx := (float64(x)*float64(World2TileSize) - g.World2.OffsetX) * g.World2.Zoom + ScreenWidth/2
+		sy := (float64(y)*float64(World2TileSize) - g.World2.OffsetY) * g.World2.Zoom + ScreenHeight/2
+		size := float64(World2TileSize) * g.World2.Zoom
 
-			var c color.Color
-			// --- タイルカラー判定 ---
-			switch tile.Type {
-			case W2TileSoil:
-				if g.Gen2.NewSoils[y*w+x] {
-					c = color.RGBA{210, 180, 140, 255}
-				} else {
-					switch tile.Source {
-					case SrcMain:
-						c = color.RGBA{180, 100, 80, 255}
-					case SrcSub:
-						c = color.RGBA{100, 160, 80, 255}
-					case SrcMix:
-						c = color.RGBA{160, 100, 160, 255}
-					case SrcBridge:
-						c = color.RGBA{150, 150, 160, 255}
-					case SrcIsland:
-						c = color.RGBA{230, 190, 100, 255}
-					case SrcBRouteIsland:
-						c = color.RGBA{100, 180, 100, 255} // B航路の経由島（緑）
-					default:
-						c = color.RGBA{139, 69, 19, 255}
-					}
-				}
-			case W2TileVariableOcean:
-				if tile.IsLake {
-					c = color.RGBA{60, 100, 200, 255}
-				} else {
-					c = color.RGBA{30, 60, 180, 255}
-				}
-				// 航路の色付け
-				if tile.Source == SrcTransitPath {
-					c = color.RGBA{40, 80, 160, 255} // A航路（青系）
-				}
-				if tile.Source == SrcBRoutePath {
-					c = color.RGBA{50, 120, 80, 255} // B航路（暗緑）
-				}
-			case W2TileFixedOcean:
-				c = color.RGBA{10, 20, 80, 255}
-			case W2TileTransit:
-				if tile.Source == SrcBRouteIsland {
-					c = color.RGBA{100, 180, 100, 255} // B航路の経由島（緑）
-				} else {
-					c = color.RGBA{200, 180, 80, 255} // A航路の経由島（黄色）
-				}
-			case W2TileCliff:
-				c = color.RGBA{80, 40, 10, 255}
-				if g.Gen2.NewSoils[y*w+x] {
-					c = color.RGBA{120, 60, 30, 255}
-				}
-			case W2TileShallow:
-				c = color.RGBA{60, 160, 200, 255}
-				if g.Gen2.NewSoils[y*w+x] {
-					c = color.RGBA{100, 200, 255, 255}
-				}
+		// --- タイルカラー判定 (PNG出力と共通のパレット) ---
+		c := world2.TileColor(tile, g.Gen2.NewSoils[y*w+x])
+		switch g.World2.RenderMode {
+		case W2RenderBiome:
+			c = world2.BiomeTileColor(tile, g.Gen2.NewSoils[y*w+x])
+		case W2RenderNation:
+			c = world2.NationTileColor(tile, g.Gen2.NewSoils[y*w+x])
+		}
+		if g.World2.ShowHillshade {
+			c = world2.ShadeColor(c, world2.Hillshade(g.World2.Tiles, x, y))
+		}
+		ebitenutil.DrawRect(screen, sx, sy, size+1, size+1, c)
+		// バイオーム表示では World1 と同じ森・山のアイコンを重ねる (十分拡大している時のみ)
+		if g.World2.RenderMode == W2RenderBiome && size >= 12 && !tile.IsRiver {
+			var icon *ebiten.Image
+			switch tile.Biome {
+			case world2.BiomeForest:
+				icon = TexW_TreeIcon
+			case world2.BiomeMountain:
+				icon = TexW_MountainIcon
 			}
-			ebitenutil.DrawRect(screen, sx, sy, size+1, size+1, c)
-			// --- タイルカラー判定 終 ---
-
-			if g.World2.ShowGrid || tile.Type == W2TileTransit {
-				if g.World2.ShowGrid {
-					ebitenutil.DrawRect(screen, sx, sy, size, 1, color.RGBA{255, 255, 255, 50})
-					ebitenutil.DrawRect(screen, sx, sy, 1, size, color.RGBA{255, 255, 255, 50})
-				}
-				if tile.Type == W2TileTransit && size > 10 {
-					text.Draw(screen, "経", basicfont.Face7x13, int(sx), int(sy+10), color.Black)
-				}
+			if icon != nil {
+				op := &ebiten.DrawImageOptions{}
+				op.GeoM.Scale(size/float64(icon.Bounds().Dx()), size/float64(icon.Bounds().Dy()))
+				op.GeoM.Translate(sx, sy)
+				screen.DrawImage(icon, op)
 			}
-			
-			// Gen Mask Imageの描画
-			if g.Gen2.CurrentStep <= Phase_SoilStart && g.Gen2.FinalMask != nil {
-				val := g.Gen2.FinalMask[x][y]
-				if val > 0 {
-					gray := uint8(val * 255)
-					ebitenutil.DrawRect(screen, sx, sy, size+1, size+1, color.RGBA{gray, gray, gray, 100})
-				}
+		}
+		// 国境線 (バイオーム・国の表示時、右と下の隣との境目に引く)
+		if g.World2.RenderMode != W2RenderSource {
+			borderCol := color.RGBA{40, 20, 20, 220}
+			if world2.IsNationBorder(g.World2.Tiles, x, y, 1, 0) {
+				ebitenutil.DrawRect(screen, sx+size-1, sy, 2, size+1, borderCol)
+			}
+			if world2.IsNationBorder(g.World2.Tiles, x, y, 0, 1) {
+				ebitenutil.DrawRect(screen, sx, sy+size-1, size+1, 2, borderCol)
 			}
 		}
+		// 道と集落 (すべての表示モードで重ねる)
+		if tile.IsRoad {
+			ebitenutil.DrawRect(screen, sx+size*0.35, sy+size*0.35, size*0.3+1, size*0.3+1, world2.RoadColor)
+		}
+		switch tile.Settlement {
+		case world2.SettleCity:
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(size/float64(TexW_CityIcon.Bounds().Dx()), size/float64(TexW_CityIcon.Bounds().Dy()))
+			op.GeoM.Translate(sx, sy)
+			screen.DrawImage(TexW_CityIcon, op)
+		case world2.SettleTown, world2.SettlePort:
+			ebitenutil.DrawRect(screen, sx+size*0.2, sy+size*0.2, size*0.6, size*0.6, world2.SettlementColor(tile.Settlement))
 		}
+		// --- タイルカラー判定 終 ---
 
-		// --- ポーズ中の強調描画 (ポーズ機能削除により非表示) ---
-		if false {
-			for _, rect := range g.World2.PinkRects {
-				sx := (float64(rect.x)*float64(World2TileSize) - g.World2.OffsetX) * g.World2.Zoom + ScreenWidth/2
-				sy := (float64(rect.y)*float64(World2TileSize) - g.World2.OffsetY) * g.World2.Zoom + ScreenHeight/2
-				w := float64(rect.w) * g.World2.Zoom
-				h := float64(rect.h) * g.World2.Zoom
+		if g.World2.ShowGrid || tile.Type == world2.W2TileTransit {
+			if g.World2.ShowGrid {
+				ebitenutil.DrawRect(screen, sx, sy, size, 1, color.RGBA{255, 255, 255, 50})
+				ebitenutil.DrawRect(screen, sx, sy, 1, size, color.RGBA{255, 255, 255, 50})
+			}
+			if tile.Type == world2.W2TileTransit && size > 10 {
+				text.Draw(screen, "経", basicfont.Face7x13, int(sx), int(sy+10), color.Black)
+			}
+		}
+		
+		// Gen Mask Imageの描画
+		if g.Gen2.CurrentStep <= world2.Phase_SoilStart && g.Gen2.FinalMask != nil {
+			val := g.Gen2.FinalMask[x][y]
+			if val > 0 {
+				gray := uint8(val * 255)
+				ebitenutil.DrawRect(screen, sx, sy, size+1, size+1, color.RGBA{gray, gray, gray, 100})
+			}
+		}
+	}
+	}
 
-				// 浅瀬の色で半透明の矩形を描画
-				ebitenutil.DrawRect(screen, sx, sy, w, h, color.RGBA{60, 160, 200, 100})
+	// --- 首都の描画 (国の表示時) ---
+	if g.World2.RenderMode == W2RenderNation {
+		for _, c := range g.World2.Capitals {
+			if c == world2.NoCapital {
+				continue
+			}
+			size := float64(World2TileSize) * g.World2.Zoom
+			sx := (float64(c.X)*float64(World2TileSize) - g.World2.OffsetX) * g.World2.Zoom + ScreenWidth/2
+			sy := (float64(c.Y)*float64(World2TileSize) - g.World2.OffsetY) * g.World2.Zoom + ScreenHeight/2
+			m := size
+			if m < 6 {
+				m = 6
 			}
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(m/float64(TexW_CityIcon.Bounds().Dx()), m/float64(TexW_CityIcon.Bounds().Dy()))
+			op.GeoM.Translate(sx+(size-m)/2, sy+(size-m)/2)
+			screen.DrawImage(TexW_CityIcon, op)
+		}
+	}
+	// --- 首都の描画 終 ---
 
-			// ポーズメッセージ
-			msg := "Paused: Large Ocean Found (Press [PgDn] or [Enter] to continue)"
-			w := len(msg) * 7
-			ebitenutil.DrawRect(screen, float64(ScreenWidth/2-w/2-10), float64(ScreenHeight/2-20), float64(w+20), 40, color.RGBA{50, 50, 0, 230})
-			text.Draw(screen, msg, basicfont.Face7x13, ScreenWidth/2-w/2, ScreenHeight/2+5, color.White)
+	// --- 経路モードの経路と始点・終点 ---
+	if g.World2.RouteMode {
+		size := float64(World2TileSize) * g.World2.Zoom
+		toScreen := func(p world2.Point) (float64, float64) {
+			return (float64(p.X)*float64(World2TileSize)-g.World2.OffsetX)*g.World2.Zoom + ScreenWidth/2,
+				(float64(p.Y)*float64(World2TileSize)-g.World2.OffsetY)*g.World2.Zoom + ScreenHeight/2
+		}
+		for _, p := range g.World2.RoutePath {
+			sx, sy := toScreen(p)
+			ebitenutil.DrawRect(screen, sx+size*0.25, sy+size*0.25, size*0.5+1, size*0.5+1, color.RGBA{255, 230, 0, 230})
+		}
+		for _, p := range g.World2.RoutePoints {
+			sx, sy := toScreen(p)
+			ebitenutil.DrawRect(screen, sx-1, sy-1, size+3, size+3, color.RGBA{255, 0, 255, 200})
+		}
+	}
+
+	// --- ポーズ中の強調描画 (ポーズ機能削除により非表示) ---
+	if false {
+		for _, rect := range g.World2.PinkRects {
+			sx := (float64(rect.X)*float64(World2TileSize) - g.World2.OffsetX) * g.World2.Zoom + ScreenWidth/2
+			sy := (float64(rect.Y)*float64(World2TileSize) - g.World2.OffsetY) * g.World2.Zoom + ScreenHeight/2
+			w := float64(rect.W) * g.World2.Zoom
+			h := float64(rect.H) * g.World2.Zoom
+
+			// 浅瀬の色で半透明の矩形を描画
+			ebitenutil.DrawRect(screen, sx, sy, w, h, color.RGBA{60, 160, 200, 100})
 		}
-		// --- ポーズ中の強調描画 終 ---
-	} // if !g.SuppressMapDraw の閉じ括弧
+
+		// ポーズメッセージ
+		msg := "Paused: Large Ocean Found (Press [PgDn] or [Enter] to continue)"
+		w := len(msg) * 7
+		ebitenutil.DrawRect(screen, float64(ScreenWidth/2-w/2-10), float64(ScreenHeight/2-20), float64(w+20), 40, color.RGBA{50, 50, 0, 230})
+		text.Draw(screen, msg, basicfont.Face7x13, ScreenWidth/2-w/2, ScreenHeight/2+5, color.White)
+	}
+	// --- ポーズ中の強調描画 終 ---
 
 
 	vectorY := 20
 	text.Draw(screen, "Phase: "+g.Gen2.PhaseName, basicfont.Face7x13, 220, 20, color.White)
-	if g.LastTargetSoil > 0 {
-		text.Draw(screen, fmt.Sprintf("Target Soil: %d%%", g.LastTargetSoil), basicfont.Face7x13, 220, 40, color.White)
+	if g.Gen2.LastTargetSoil > 0 {
+		text.Draw(screen, fmt.Sprintf("Target Soil: %d%%", g.Gen2.LastTargetSoil), basicfont.Face7x13, 220, 40, color.White)
+	}
+	// 裏で生成中: 段ごとに区切った進捗バー (終わった段は緑、実行中の段は黄色)
+	if g.W2Job != nil {
+		p := g.W2Job.Progress()
+		if p.Total > 0 {
+			seg := 400.0 / float64(p.Total)
+			for i := 0; i < p.Total; i++ {
+				c := color.RGBA{60, 60, 60, 220}
+				if i < p.Done {
+					c = color.RGBA{60, 180, 80, 230}
+				} else if i == p.Done {
+					c = color.RGBA{230, 200, 40, 230}
+				}
+				ebitenutil.DrawRect(screen, 220+float64(i)*seg, 50, seg-1, 10, c)
+			}
+		}
+		text.Draw(screen, fmt.Sprintf("Generating %d/%d: %s  [Enter] Cancel", p.Done, p.Total, p.Running), basicfont.Face7x13, 220, 75, color.White)
+	}
+	// 完成したマップの指標と、シード探索の進み具合
+	for i, line := range g.world2MetricsLines() {
+		text.Draw(screen, line, basicfont.Face7x13, 220, 95+i*15, color.White)
+	}
+	if g.W2Search != nil {
+		tried, seed := g.W2Search.Tried()
+		text.Draw(screen, fmt.Sprintf("Seed search [%s]: %d/%d (seed %d)  [F] Cancel", g.W2SeedSearch, tried, g.W2SeedTries, seed), basicfont.Face7x13, 220, 125, color.RGBA{120, 255, 255, 255})
 	}
 
 	for _, s := range g.World2.StatsInfo {
@@ -605,6 +993,8 @@ func (g *Game) DrawWorld2(screen *ebiten.Image) {
 			txt = fmt.Sprintf("%s: %d", label, v)
 		case float64:
 			txt = fmt.Sprintf("%s: %.2f", label, v)
+		case int64:
+			txt = fmt.Sprintf("%s: %d", label, v)
 		}
 
 		if g.InputMode == mode {
@@ -621,14 +1011,18 @@ func (g *Game) DrawWorld2(screen *ebiten.Image) {
 
 	drawInputBox(300, "Ratio", g.MapRatio, EditMapRatio)
 
-	cenColor := color.RGBA{50, 0, 0, 200}
-	cenText := "OFF"
-	if g.EnableCentering {
-		cenColor = color.RGBA{0, 100, 0, 200}
-		cenText = "ON"
+	drawToggle := func(x, w int, label string, on bool) {
+		c := color.RGBA{50, 0, 0, 200}
+		txt := "OFF"
+		if on {
+			c = color.RGBA{0, 100, 0, 200}
+			txt = "ON"
+		}
+		ebitenutil.DrawRect(screen, float64(x), 340, float64(w), 30, c)
+		text.Draw(screen, label+": "+txt, basicfont.Face7x13, x+8, 360, color.White)
 	}
-	ebitenutil.DrawRect(screen, 10, 340, 200, 30, cenColor)
-	text.Draw(screen, "Centering: "+cenText, basicfont.Face7x13, 20, 360, color.White)
+	drawToggle(10, 95, "Center", g.EnableCentering)
+	drawToggle(115, 95, "IsleShl", g.EnableIslandShallow)
 
 	drawInputBox(380, "Cliff Init", g.CliffInitVal, EditCliffInit)
 	drawInputBox(420, "Cliff Dec", g.CliffDecVal, EditCliffDec)
@@ -636,6 +1030,65 @@ func (g *Game) DrawWorld2(screen *ebiten.Image) {
 	drawInputBox(500, "Cliff Path", g.CliffPathLen, EditCliffPath)
 	drawInputBox(540, "Force Turn", g.ForceSwitch, EditForceSwitch)
 
-	text.Draw(screen, "[PgDn] Next, [PgUp] Back, [Enter] All", basicfont.Face7x13, 10, 670, color.White)
-	text.Draw(screen, "[Drag]: Move, [Ctrl+Wheel]: Zoom, [R]: Reset", basicfont.Face7x13, 10, ScreenHeight-20, color.White)
+	// 現在表示中のマップの初期シード (W2Seed=0 の間はリセットごとに変わるため * を付ける)
+	seedLabel := "Seed"
+	if g.W2Seed == 0 {
+		seedLabel = "Seed*"
+	}
+	drawInputBox(580, seedLabel, g.Gen2.StartSeed, EditSeed)
+
+	drawInputBox(620, "Main Type", g.MainType, EditMainType)
+	drawInputBox(660, "Sub Type", g.SubType, EditSubType)
+
+	// タイムライン: 全スナップショットの PhaseName (現在位置は黄色、Redo で戻せる段は灰色)
+	current := len(g.Gen2.History) - 1
+	ebitenutil.DrawRect(screen, World2TimelineX, World2TimelineY-4, World2TimelineW, float64(g.Gen2.TimelineLen()*World2TimelineRowH+8), color.RGBA{20, 20, 40, 200})
+	for i := 0; i < g.Gen2.TimelineLen(); i++ {
+		label := fmt.Sprintf("%02d %s", i, g.Gen2.TimelineSnapshot(i).PhaseName)
+		if maxChars := World2TimelineW/7 - 1; len(label) > maxChars {
+			label = label[:maxChars]
+		}
+		var c color.Color = color.White
+		if i == current {
+			ebitenutil.DrawRect(screen, World2TimelineX, float64(World2TimelineY+i*World2TimelineRowH), World2TimelineW, World2TimelineRowH, color.RGBA{80, 80, 20, 220})
+			c = color.RGBA{255, 255, 120, 255}
+		} else if i > current {
+			c = color.RGBA{130, 130, 130, 255}
+		}
+		text.Draw(screen, label, basicfont.Face7x13, World2TimelineX+4, World2TimelineY+i*World2TimelineRowH+11, c)
+	}
+
+	// 経路モード: 移動ターン数と時間 (1ターン = BaseTurnToMin 分)
+	if g.World2.RouteMode {
+		routeStr := "Route: click start and goal"
+		if len(g.World2.RoutePath) > 0 {
+			mins := g.World2.RouteTurns * BaseTurnToMin
+			routeStr = fmt.Sprintf("Route: %d tiles, %d turns (%dh%02dm)", len(g.World2.RoutePath)-1, g.World2.RouteTurns, mins/60, mins%60)
+		}
+		text.Draw(screen, routeStr, basicfont.Face7x13, 220, 640, color.RGBA{255, 230, 0, 255})
+	}
+
+	// 手描きモード: 筆の状態と、カーソル位置の筆の範囲
+	if g.World2.PaintMode {
+		b := g.World2.Brush
+		if p, ok := g.world2TileAt(ebiten.CursorPosition()); ok {
+			r := b.Size
+			if b.Shape == world2.BrushFill {
+				r = 0
+			}
+			size := float64(World2TileSize) * g.World2.Zoom
+			sx := (float64(p.X-r)*float64(World2TileSize)-g.World2.OffsetX)*g.World2.Zoom + ScreenWidth/2
+			sy := (float64(p.Y-r)*float64(World2TileSize)-g.World2.OffsetY)*g.World2.Zoom + ScreenHeight/2
+			ebitenutil.DrawRect(screen, sx, sy, size*float64(2*r+1), size*float64(2*r+1), color.RGBA{255, 255, 255, 60})
+		}
+		paintStr := fmt.Sprintf("Paint: %s Src%d %s r%d  [1-6] Type [O] Src [Tab] Shape [-/=] Size [K] Lakes",
+			world2.TileTypeNames[b.Type], b.Source, world2.BrushShapeNames[b.Shape], b.Size)
+		text.Draw(screen, paintStr, basicfont.Face7x13, 220, 625, color.RGBA{255, 160, 255, 255})
+	}
+
+	// 操作説明は入力パネルの右側に表示
+	text.Draw(screen, "[PgDn] Next/Redo, [PgUp] Back, [Enter] All/Cancel, [B] Branch", basicfont.Face7x13, 220, 655, color.White)
+	text.Draw(screen, "[Click Timeline] Jump, [E] Hillshade, [V] Source/Biome/Nation, [M] Paint", basicfont.Face7x13, 220, 670, color.White)
+	text.Draw(screen, "[S] Save, [L] Load, [P] PNG, [H] History PNGs, [T] Route, [F] Seed Search", basicfont.Face7x13, 220, 685, color.White)
+	text.Draw(screen, "[Drag]: Move, [Ctrl+Wheel]: Zoom, [R]: Reset", basicfont.Face7x13, 220, ScreenHeight-20, color.White)
 }
\ No newline at end of file
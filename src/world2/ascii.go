@@ -0,0 +1,59 @@
+// filename: world2/ascii.go
+package world2
+
+import (
+	"bufio"
+	"io"
+)
+
+// asciiTile はタイル種別ごとの1文字表現
+func asciiTile(t World2Tile) byte {
+	switch t.Settlement {
+	case SettleCity:
+		return 'C'
+	case SettleTown:
+		return 't'
+	case SettlePort:
+		return 'P'
+	}
+	if t.IsRoad && isLandType(t.Type) {
+		return '+'
+	}
+	switch t.Type {
+	case W2TileSoil:
+		if t.IsRiver {
+			return 'r'
+		}
+		return '#'
+	case W2TileFixedOcean:
+		return '~'
+	case W2TileTransit:
+		return 'T'
+	case W2TileCliff:
+		return '^'
+	case W2TileShallow:
+		return ','
+	}
+	if t.IsLake {
+		return 'o'
+	}
+	if t.Source == SrcTransitPath || t.Source == SrcBRoutePath {
+		return '-'
+	}
+	if t.Source == SrcRoute2Path {
+		return '='
+	}
+	return '.'
+}
+
+// WriteASCII はマップを1タイル1文字のテキストとして書き出す
+func (m *WorldMap2) WriteASCII(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			bw.WriteByte(asciiTile(m.Tiles[x][y]))
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
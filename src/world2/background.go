@@ -0,0 +1,184 @@
+// filename: world2/background.go
+package world2
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress は裏で進めている生成の進み具合
+type Progress struct {
+	Done, Total int    // 終わった段の数と全段数 (Phases 上の位置。飛ばした段も数える)
+	PhaseName   string // 最後に終わった段の名前
+	Running     string // 実行中 (次に実行する) 段の名前
+}
+
+// BackgroundRun は生成器を別の goroutine で最後まで (または Cancel まで) 進める
+// 実行中は元の生成器に触らず、Progress と Preview で様子を見る。終わったら Wait で受け取る
+type BackgroundRun struct {
+	gen    *World2Generator
+	every  time.Duration
+	cancel chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	mu       sync.Mutex
+	progress Progress
+	preview  *World2Generator
+	seq      int // preview を作り直した回数
+	canceled bool
+}
+
+// RunInBackground は gen を別の goroutine で最後の段まで進める
+// 途中の状態は previewEvery ごと (段の切れ目) に複製され、Preview で読める
+// 呼び出した時点の状態の複製が最初の Preview になる
+func (gen *World2Generator) RunInBackground(previewEvery time.Duration) *BackgroundRun {
+	b := &BackgroundRun{
+		gen:    gen,
+		every:  previewEvery,
+		cancel: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	b.progress = gen.progress()
+	b.preview = gen.previewCopy()
+	go b.run()
+	return b
+}
+
+func (b *BackgroundRun) run() {
+	defer close(b.done)
+	gen := b.gen
+	gen.interrupt = b.cancel
+	defer func() { gen.interrupt = nil }()
+	last := time.Now()
+	for !gen.IsFinished {
+		select {
+		case <-b.cancel:
+			b.mu.Lock()
+			b.canceled = true
+			b.mu.Unlock()
+			return
+		default:
+		}
+		gen.NextStep()
+
+		p := gen.progress()
+		var preview *World2Generator
+		if !gen.IsFinished && time.Since(last) >= b.every {
+			preview = gen.previewCopy()
+			last = time.Now()
+		}
+		b.mu.Lock()
+		b.progress = p
+		if preview != nil {
+			b.preview = preview
+			b.seq++
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Cancel は生成を止める。土の配置と崖の段は途中で打ち切って段の前の状態に戻し、それ以外の段は最後まで進めてから止まる
+func (b *BackgroundRun) Cancel() {
+	b.once.Do(func() { close(b.cancel) })
+}
+
+// Done は goroutine が終わったか (最後まで生成したか、止まったか) を返す
+func (b *BackgroundRun) Done() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait は goroutine が終わるまで待ち、生成器を返す
+// 止めた場合も、終わった段までの History を持つ通常の生成器として続きを進められる
+func (b *BackgroundRun) Wait() *World2Generator {
+	<-b.done
+	return b.gen
+}
+
+// Canceled は Cancel によって最後の段より前で止まったかを返す
+func (b *BackgroundRun) Canceled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.canceled
+}
+
+// Progress は直近に終わった段までの進み具合を返す
+func (b *BackgroundRun) Progress() Progress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.progress
+}
+
+// Preview は途中の状態の複製と、その通し番号 (作り直すたびに増える) を返す
+// 複製は表示専用で、生成を進めることはできない
+func (b *BackgroundRun) Preview() (*World2Generator, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.preview, b.seq
+}
+
+// stopRequested は interrupt が閉じられていれば実行中の段を打ち切る印を付けて真を返す
+// 長い繰り返しを持つ段が、繰り返しの頭で呼んで真ならそのまま return する
+func (gen *World2Generator) stopRequested() bool {
+	select {
+	case <-gen.interrupt:
+		gen.aborted = true
+		return true
+	default:
+		return false
+	}
+}
+
+// progress は生成器の現在の進み具合を返す
+func (gen *World2Generator) progress() Progress {
+	p := Progress{Done: gen.PhaseIndex, Total: len(gen.Phases), PhaseName: gen.PhaseName}
+	if gen.PhaseIndex < len(gen.Phases) {
+		p.Running = gen.Phases[gen.PhaseIndex].Name
+	}
+	return p
+}
+
+// previewCopy は表示に使う部分だけを複製した生成器を返す
+// タイル・統計・新しい土は複製し、段の後で書き換えられないマスクは共有する。History と Redo は段の名前だけを持つ
+func (gen *World2Generator) previewCopy() *World2Generator {
+	m := *gen.World2
+	m.Tiles = copyTiles(gen.World2.Tiles)
+	m.StatsInfo = append([]string(nil), m.StatsInfo...)
+	m.PinkRects = append([]Rect(nil), m.PinkRects...)
+	m.Capitals = append([]Point(nil), m.Capitals...)
+
+	names := func(snaps []GenSnapshot) []GenSnapshot {
+		out := make([]GenSnapshot, len(snaps))
+		for i, s := range snaps {
+			out[i] = GenSnapshot{PhaseName: s.PhaseName, StepID: s.StepID, PhaseIndex: s.PhaseIndex}
+		}
+		return out
+	}
+	newSoils := make(map[int]bool, len(gen.NewSoils))
+	for k, v := range gen.NewSoils {
+		newSoils[k] = v
+	}
+	return &World2Generator{
+		CurrentStep:    gen.CurrentStep,
+		IsFinished:     gen.IsFinished,
+		PhaseName:      gen.PhaseName,
+		History:        names(gen.History),
+		Redo:           names(gen.Redo),
+		Phases:         gen.Phases,
+		PhaseIndex:     gen.PhaseIndex,
+		World2:         &m,
+		StartSeed:      gen.StartSeed,
+		CurrentSeed:    gen.CurrentSeed,
+		MaskMain:       gen.MaskMain,
+		MaskSub:        gen.MaskSub,
+		FinalMask:      gen.FinalMask,
+		Config:         gen.Config,
+		NewSoils:       newSoils,
+		LastTargetSoil: gen.LastTargetSoil,
+	}
+}
@@ -0,0 +1,98 @@
+// filename: world2/background_test.go
+package world2
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// 裏で最後まで進めた結果が、同じシードで Run した結果と同じになること
+func TestRunInBackgroundMatchesRun(t *testing.T) {
+	cfg := DefaultConfig()
+	want := newTestGenerator(t, cfg, 9)
+	want.Run()
+
+	gen := newTestGenerator(t, cfg, 9)
+	run := gen.RunInBackground(0)
+	if got := run.Wait(); got != gen || !gen.IsFinished || run.Canceled() {
+		t.Fatalf("finished %v canceled %v", gen.IsFinished, run.Canceled())
+	}
+	if !bytes.Equal(tileBytes(gen.World2), tileBytes(want.World2)) {
+		t.Errorf("tiles differ from Run")
+	}
+	if p := run.Progress(); p.Done != p.Total || p.PhaseName != want.PhaseName {
+		t.Errorf("progress %+v, want done and phase %q", p, want.PhaseName)
+	}
+}
+
+// 止めると実行中の段の後で止まり、続きを手元で進められること。途中の複製は元の生成器と独立していること
+func TestRunInBackgroundCancel(t *testing.T) {
+	entered, release := make(chan bool), make(chan bool)
+	withTestPhase(t, "test-block", Phase{ID: 99, Name: "Block", Run: func(gen *World2Generator, w, h int, rng *rand.Rand) {
+		entered <- true
+		<-release
+	}})
+	cfg := DefaultConfig()
+	cfg.Phases = []string{"init", "test-block", "mask"}
+	gen := newTestGenerator(t, cfg, 3)
+
+	run := gen.RunInBackground(0)
+	<-entered
+	preview, seq := run.Preview()
+	if p := run.Progress(); p.Done != 1 || p.Running != "Block" {
+		t.Errorf("progress while blocked %+v", p)
+	}
+	run.Cancel()
+	run.Cancel() // 二度呼んでもよい
+	close(release)
+	run.Wait()
+
+	if !run.Canceled() || gen.IsFinished || gen.PhaseIndex != 2 {
+		t.Fatalf("canceled %v finished %v index %d", run.Canceled(), gen.IsFinished, gen.PhaseIndex)
+	}
+	if _, s := run.Preview(); s <= seq {
+		t.Errorf("preview not refreshed after the blocking phase (seq %d -> %d)", seq, s)
+	}
+	preview.World2.Tiles[5][5].Type = W2TileCliff
+	if gen.World2.Tiles[5][5].Type == W2TileCliff {
+		t.Errorf("preview shares tiles with the generator")
+	}
+	if preview.TimelineLen() != 2 || !strings.HasPrefix(preview.TimelineSnapshot(1).PhaseName, "0. Init") {
+		t.Errorf("preview timeline %d %q", preview.TimelineLen(), preview.TimelineSnapshot(1).PhaseName)
+	}
+
+	gen.Run()
+	if !gen.IsFinished || !strings.HasPrefix(gen.PhaseName, "1. Mask") {
+		t.Errorf("resume: finished %v phase %q", gen.IsFinished, gen.PhaseName)
+	}
+}
+
+// 止められた土の配置と崖の段は途中で打ち切られて段の前の状態に戻り、続きを進めると止めなかった時と同じ結果になること
+func TestInterruptRollsBackLongPhases(t *testing.T) {
+	cfg := DefaultConfig()
+	want := newTestGenerator(t, cfg, 12)
+	want.Run()
+
+	stop := make(chan struct{})
+	close(stop)
+	for _, step := range []int{Phase_SoilStart, Phase_SoilStart + 4, Phase_CliffsShallows} {
+		gen := newTestGenerator(t, cfg, 12)
+		for gen.CurrentStep < step {
+			gen.NextStep()
+		}
+		before, n, index := tileBytes(gen.World2), gen.TimelineLen(), gen.PhaseIndex
+
+		gen.interrupt = stop
+		gen.NextStep()
+		gen.interrupt = nil
+		if !bytes.Equal(tileBytes(gen.World2), before) || gen.TimelineLen() != n || gen.PhaseIndex != index || gen.CurrentStep != step {
+			t.Fatalf("step %d: interrupted step was not rolled back (timeline %d->%d, index %d->%d)", step, n, gen.TimelineLen(), index, gen.PhaseIndex)
+		}
+		gen.Run()
+		if !bytes.Equal(tileBytes(gen.World2), tileBytes(want.World2)) {
+			t.Errorf("step %d: resumed run differs from an uninterrupted run", step)
+		}
+	}
+}
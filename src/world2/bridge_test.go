@@ -0,0 +1,94 @@
+// filename: world2/bridge_test.go
+package world2
+
+import "testing"
+
+// Bresenham4 の線は端点を含み、隣り合うマスが常に4近傍でつながること
+func TestBresenham4Connected(t *testing.T) {
+	for _, c := range [][4]int{{0, 0, 7, 3}, {5, 5, 0, 0}, {2, 9, 8, 1}, {4, 4, 4, 4}} {
+		var pts [][2]int
+		Bresenham4(c[0], c[1], c[2], c[3], func(x, y int) { pts = append(pts, [2]int{x, y}) })
+		if pts[0] != [2]int{c[0], c[1]} || pts[len(pts)-1] != [2]int{c[2], c[3]} {
+			t.Fatalf("%v: endpoints %v .. %v", c, pts[0], pts[len(pts)-1])
+		}
+		for i := 1; i < len(pts); i++ {
+			dx, dy := pts[i][0]-pts[i-1][0], pts[i][1]-pts[i-1][1]
+			if dx*dx+dy*dy != 1 {
+				t.Fatalf("%v: step %v -> %v is not 4-connected", c, pts[i-1], pts[i])
+			}
+		}
+	}
+}
+
+// Type 8 では橋を架けた後、bridgeMinSize 以上の土の塊がすべて1つにつながり、外周3マスは固定海のままであること
+func TestBridgeLinksAllLandMasses(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MainType, cfg.SubType = MaskLinkedIsles, MaskLinkedIsles
+	gen := newTestGenerator(t, cfg, 5)
+	for gen.CurrentStep <= Phase_Bridge {
+		gen.NextStep()
+	}
+	m := gen.World2
+	w, h := m.Width, m.Height
+
+	bridges := 0
+	comp := make([]int, w*h)
+	for i := range comp {
+		comp[i] = -1
+	}
+	var sizes []int
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			t0 := m.Tiles[x][y]
+			if (x < 3 || x >= w-3 || y < 3 || y >= h-3) && t0.Type != W2TileFixedOcean {
+				t.Fatalf("border tile (%d,%d) changed to type %d", x, y, t0.Type)
+			}
+			if t0.Source == SrcBridge {
+				bridges++
+			}
+			if t0.Type != W2TileSoil || comp[y*w+x] >= 0 {
+				continue
+			}
+			id := len(sizes)
+			size := 0
+			stack := [][2]int{{x, y}}
+			comp[y*w+x] = id
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				size++
+				for _, d := range [][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}} {
+					nx, ny := p[0]+d[0], p[1]+d[1]
+					if m.Tiles[nx][ny].Type == W2TileSoil && comp[ny*w+nx] < 0 {
+						comp[ny*w+nx] = id
+						stack = append(stack, [2]int{nx, ny})
+					}
+				}
+			}
+			sizes = append(sizes, size)
+		}
+	}
+	if bridges == 0 {
+		t.Fatalf("no bridge tiles placed (%s)", gen.PhaseName)
+	}
+	big := 0
+	for _, s := range sizes {
+		if s >= bridgeMinSize {
+			big++
+		}
+	}
+	if big != 1 {
+		t.Errorf("%d land masses of size >= %d remain after bridging, want 1", big, bridgeMinSize)
+	}
+}
+
+// Type 8 を使わない設定では橋フェーズは何も変更しないこと
+func TestBridgeSkippedWithoutLinkedIsles(t *testing.T) {
+	gen := newTestGenerator(t, DefaultConfig(), 5)
+	for gen.CurrentStep <= Phase_Bridge {
+		gen.NextStep()
+	}
+	if len(gen.NewSoils) != 0 || gen.PhaseName != "13. Bridge (Skipped)" {
+		t.Errorf("bridge phase ran for classic mask: %q, %d new tiles", gen.PhaseName, len(gen.NewSoils))
+	}
+}
@@ -0,0 +1,66 @@
+// filename: world2/candidates.go
+package world2
+
+// candidateSet は 0..n-1 の位置の集合を、位置の順序を保ったまま持つ (Fenwick 木)
+// 追加・削除と「小さい方から k 番目」の取り出しがどれも O(log n) でできる
+type candidateSet struct {
+	tree  []int32 // 1 始まりの Fenwick 木
+	in    []bool
+	count int
+}
+
+// newCandidateSet は member(i) が真の位置からなる集合を O(n) で作る
+func newCandidateSet(n int, member func(i int) bool) *candidateSet {
+	s := &candidateSet{tree: make([]int32, n+1), in: make([]bool, n)}
+	for i := 0; i < n; i++ {
+		if member(i) {
+			s.in[i] = true
+			s.tree[i+1]++
+			s.count++
+		}
+		if j := i + 1 + (i+1)&-(i+1); j <= n {
+			s.tree[j] += s.tree[i+1]
+		}
+	}
+	return s
+}
+
+// has は位置 i が集合に含まれるかを返す
+func (s *candidateSet) has(i int) bool {
+	return s.in[i]
+}
+
+// set は位置 i を集合に入れる (on が偽なら外す)
+func (s *candidateSet) set(i int, on bool) {
+	if s.in[i] == on {
+		return
+	}
+	s.in[i] = on
+	d := int32(1)
+	if on {
+		s.count++
+	} else {
+		d = -1
+		s.count--
+	}
+	for j := i + 1; j < len(s.tree); j += j & -j {
+		s.tree[j] += d
+	}
+}
+
+// pick は集合のうち小さい方から k 番目 (0 始まり) の位置を返す。0 <= k < count であること
+func (s *candidateSet) pick(k int) int {
+	pos := 0
+	step := 1
+	for step*2 < len(s.tree) {
+		step *= 2
+	}
+	rest := int32(k) + 1
+	for ; step > 0; step /= 2 {
+		if next := pos + step; next < len(s.tree) && s.tree[next] < rest {
+			pos = next
+			rest -= s.tree[next]
+		}
+	}
+	return pos
+}
@@ -0,0 +1,126 @@
+// filename: world2/cliffs_test.go
+package world2
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"testing"
+)
+
+// cliffCase は崖と浅瀬の段の出力を固定値と比べる生成条件
+type cliffCase struct {
+	w, h             int
+	seed             int64
+	main, sub, ratio int
+	dec              float64 // 0 以外なら CliffDec と ShallowDec をこの値にして繰り返しを増やす
+	cliffs, final    uint64  // 崖と浅瀬の段の直後と完成後の Tiles のハッシュ
+}
+
+var cliffCases = []cliffCase{
+	{w: 100, h: 70, seed: 1, main: 1, sub: 1, ratio: 10, cliffs: 0xd6b56b3c81927796, final: 0xf210b56160c73963},
+	{w: 100, h: 70, seed: 42, main: 3, sub: 7, ratio: 6, cliffs: 0x5cd7286e72bc7d63, final: 0xb895abeb39ddf8ea},
+	{w: 150, h: 100, seed: 7, main: 8, sub: 2, ratio: 4, cliffs: 0xbfced940f85a9d92, final: 0xb30db90b4141085d},
+	{w: 220, h: 140, seed: 20251018, main: 9, sub: 6, ratio: 5, cliffs: 0x1bd9a5ed6ee9e9c3, final: 0x70b43debfd023eda},
+	{w: 220, h: 140, seed: 11, main: 2, sub: 5, ratio: 7, dec: 0.005, cliffs: 0x709740ccd0cba353, final: 0xc893b82ad42e6639},
+	{w: MaxSize, h: MaxSize, seed: 3, main: 1, sub: 1, ratio: 10, cliffs: 0xecbbd0792abe099a, final: 0x604eb11ea0755bf2},
+	{w: MaxSize, h: MaxSize, seed: 4, main: 7, sub: 3, ratio: 5, dec: 0.005, cliffs: 0xdca9837633c79d5d, final: 0xf9b80a749257390c},
+}
+
+func tileHash(m *WorldMap2) uint64 {
+	f := fnv.New64a()
+	f.Write(tileBytes(m))
+	return f.Sum64()
+}
+
+// 崖と浅瀬の段は、作り直し前の実装と同じシードから同じ地形を作ること
+func TestCliffsShallowsOutputUnchanged(t *testing.T) {
+	for _, c := range cliffCases {
+		if testing.Short() && c.w*c.h > 200*200 {
+			continue
+		}
+		cfg := DefaultConfig()
+		cfg.W, cfg.H = c.w, c.h
+		cfg.MainType, cfg.SubType, cfg.Ratio = c.main, c.sub, c.ratio
+		if c.dec != 0 {
+			cfg.CliffDec, cfg.ShallowDec = c.dec, c.dec
+		}
+		gen := newTestGenerator(t, cfg, c.seed)
+		for gen.CurrentStep <= Phase_CliffsShallows {
+			gen.NextStep()
+		}
+		cliffs := tileHash(gen.World2)
+		gen.Run()
+		final := tileHash(gen.World2)
+		if cliffs != c.cliffs || final != c.final {
+			t.Errorf("%dx%d seed %d: hashes %#x/%#x, want %#x/%#x", c.w, c.h, c.seed, cliffs, final, c.cliffs, c.final)
+		}
+	}
+}
+
+// candidateSet の k 番目が、集合を小さい順に並べた k 番目と一致すること
+func TestCandidateSetPick(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 7, 64, 1000} {
+		in := make([]bool, n)
+		for i := range in {
+			in[i] = rng.Intn(3) == 0
+		}
+		s := newCandidateSet(n, func(i int) bool { return in[i] })
+		for round := 0; round < 200; round++ {
+			var want []int
+			for i, ok := range in {
+				if ok != s.has(i) {
+					t.Fatalf("n %d: has(%d) %v, want %v", n, i, s.has(i), ok)
+				}
+				if ok {
+					want = append(want, i)
+				}
+			}
+			if s.count != len(want) {
+				t.Fatalf("n %d: count %d, want %d", n, s.count, len(want))
+			}
+			for k, i := range want {
+				if got := s.pick(k); got != i {
+					t.Fatalf("n %d: pick(%d) %d, want %d", n, k, got, i)
+				}
+			}
+			i := rng.Intn(n)
+			in[i] = !in[i]
+			s.set(i, in[i])
+		}
+	}
+}
+
+// benchCliffs は崖と浅瀬の段の直前まで進めた生成器で、その段だけの時間を測る
+func benchCliffs(b *testing.B, c cliffCase) {
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = c.w, c.h
+	cfg.MainType, cfg.SubType, cfg.Ratio = c.main, c.sub, c.ratio
+	if c.dec != 0 {
+		cfg.CliffDec, cfg.ShallowDec = c.dec, c.dec
+	}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		gen, err := NewGenerator(cfg, c.seed)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for gen.CurrentStep < Phase_CliffsShallows {
+			gen.NextStep()
+		}
+		b.StartTimer()
+		gen.NextStep()
+	}
+}
+
+func BenchmarkCliffsShallows(b *testing.B) {
+	for _, c := range cliffCases {
+		name := fmt.Sprintf("%dx%d/seed%d", c.w, c.h, c.seed)
+		if c.dec != 0 {
+			name += fmt.Sprintf("/dec%g", c.dec)
+		}
+		c := c
+		b.Run(name, func(b *testing.B) { benchCliffs(b, c) })
+	}
+}
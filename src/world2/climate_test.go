@@ -0,0 +1,62 @@
+// filename: world2/climate_test.go
+package world2
+
+import "testing"
+
+func TestClimateByLatitudeAndElevation(t *testing.T) {
+	const h = 101
+	equator, _ := climate(World2Tile{Elevation: 10}, 50, h, 1)
+	pole, _ := climate(World2Tile{Elevation: 10}, 0, h, 1)
+	peak, _ := climate(World2Tile{Elevation: ElevationMax}, 50, h, 1)
+	if !(equator > peak && peak > pole) {
+		t.Errorf("temp equator %.2f, peak %.2f, pole %.2f", equator, peak, pole)
+	}
+	_, wet := climate(World2Tile{}, 50, h, 1)
+	_, dry := climate(World2Tile{}, 50, h, climateMoistRange)
+	if wet <= dry {
+		t.Errorf("moisture near water %.2f not above inland %.2f", wet, dry)
+	}
+}
+
+// 陸地には水域以外のバイオームが付き、崖は山岳、ツンドラは高緯度、砂漠は低中緯度にだけ現れること
+func TestBiomesAssigned(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		m, err := Generate(DefaultConfig(), seed)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		var counts [BiomeCount]int
+		half := float64(m.Height-1) / 2
+		for x := 0; x < m.Width; x++ {
+			for y := 0; y < m.Height; y++ {
+				tile := m.Tiles[x][y]
+				lat := (float64(y) - half) / half
+				if lat < 0 {
+					lat = -lat
+				}
+				if !isLandType(tile.Type) {
+					if tile.Biome != BiomeOcean {
+						t.Fatalf("seed %d: water (%d,%d) has biome %d", seed, x, y, tile.Biome)
+					}
+					continue
+				}
+				if tile.Biome == BiomeOcean || tile.Biome >= BiomeCount {
+					t.Fatalf("seed %d: land (%d,%d) has biome %d", seed, x, y, tile.Biome)
+				}
+				counts[tile.Biome]++
+				if tile.Type == W2TileCliff && tile.Biome != BiomeMountain {
+					t.Errorf("seed %d: cliff (%d,%d) is biome %d", seed, x, y, tile.Biome)
+				}
+				if tile.Biome == BiomeTundra && lat < 0.5 {
+					t.Errorf("seed %d: tundra at latitude %.2f", seed, lat)
+				}
+				if tile.Biome == BiomeDesert && lat > 1-climateDesertTemp {
+					t.Errorf("seed %d: desert at latitude %.2f", seed, lat)
+				}
+			}
+		}
+		if counts[BiomePlains] == 0 || counts[BiomeForest] == 0 || counts[BiomeMountain] == 0 {
+			t.Errorf("seed %d: missing common biomes %v", seed, counts)
+		}
+	}
+}
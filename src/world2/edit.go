@@ -0,0 +1,157 @@
+// filename: world2/edit.go
+package world2
+
+import "fmt"
+
+// 筆の形 (Brush.Shape)
+const (
+	BrushSquare = iota // 一辺 2*Size+1 の四角
+	BrushRound         // 半径 Size の円
+	BrushFill          // クリックしたマスと同じ種類・生成元の4方向のつながりを塗りつぶす
+
+	BrushShapeCount
+)
+
+// BrushShapeNames は画面に出す筆の形の名前 (Brush* の順)
+var BrushShapeNames = []string{"Square", "Round", "Fill"}
+
+// TileTypeNames は画面に出すタイルの種類の名前 (W2Tile* の順)
+var TileTypeNames = []string{"Ocean", "Soil", "Fixed Ocean", "Transit", "Cliff", "Shallow"}
+
+// Brush は手でマップを描く筆
+type Brush struct {
+	Type   int // 塗る W2Tile*
+	Source int // 塗る Src*
+	Size   int // 四角と円の半径 (0 で1マス)
+	Shape  int // Brush*
+}
+
+// Paint は (x, y) に筆を置いてタイルを塗り、変わったタイルの数を返す
+// 塗ったタイルは種類と生成元だけを持ち、標高以外 (湖・川・バイオーム・国・集落・道) は消える。首都を塗るとその首都は NoCapital になる
+// 外周3マスの固定海は塗らない。変更は CommitEdit で History に記録するまで Undo の対象にならない
+func (gen *World2Generator) Paint(x, y int, b Brush) int {
+	w, h := gen.World2.Width, gen.World2.Height
+	inner := func(x, y int) bool { return x >= 3 && x < w-3 && y >= 3 && y < h-3 }
+	if !inner(x, y) {
+		return 0
+	}
+	changed := 0
+	paint := func(x, y int) {
+		t := &gen.World2.Tiles[x][y]
+		nt := World2Tile{Type: b.Type, Source: b.Source, Elevation: t.Elevation}
+		if *t == nt {
+			return
+		}
+		*t = nt
+		gen.dropCapital(x, y)
+		if gen.pendingEdit == nil {
+			gen.pendingEdit = make(map[int]bool)
+		}
+		gen.pendingEdit[y*w+x] = true
+		changed++
+	}
+
+	switch b.Shape {
+	case BrushFill:
+		start := gen.World2.Tiles[x][y]
+		if start.Type == b.Type && start.Source == b.Source {
+			return 0
+		}
+		same := func(x, y int) bool {
+			t := gen.World2.Tiles[x][y]
+			return inner(x, y) && t.Type == start.Type && t.Source == start.Source
+		}
+		seen := make([]bool, w*h)
+		seen[y*w+x] = true
+		queue := []Point{{x, y}}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+				nx, ny := p.X+d.X, p.Y+d.Y
+				if nx >= 0 && nx < w && ny >= 0 && ny < h && !seen[ny*w+nx] && same(nx, ny) {
+					seen[ny*w+nx] = true
+					queue = append(queue, Point{nx, ny})
+				}
+			}
+			paint(p.X, p.Y)
+		}
+	default:
+		r := b.Size
+		if r < 0 {
+			r = 0
+		}
+		for dx := -r; dx <= r; dx++ {
+			for dy := -r; dy <= r; dy++ {
+				if b.Shape == BrushRound && dx*dx+dy*dy > r*r+r {
+					continue
+				}
+				if inner(x+dx, y+dy) {
+					paint(x+dx, y+dy)
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// dropCapital は (x, y) の首都を NoCapital にする (枠は残し、ほかの首都と国の対応は変えない)
+// スナップショットと共有しないように、一覧は複製してから書き換える
+func (gen *World2Generator) dropCapital(x, y int) {
+	for i, c := range gen.World2.Capitals {
+		if c.X == x && c.Y == y {
+			caps := append([]Point(nil), gen.World2.Capitals...)
+			caps[i] = NoCapital
+			gen.World2.Capitals = caps
+			return
+		}
+	}
+}
+
+// CommitEdit は Paint で変えたタイルを、name という段として History に記録する (UndoStep で戻せる)
+// 変えたタイルがなければ何もせず偽を返す。記録すると Redo は捨てる
+func (gen *World2Generator) CommitEdit(name string) bool {
+	if len(gen.pendingEdit) == 0 {
+		return false
+	}
+	gen.Redo = nil
+	gen.NewSoils = gen.pendingEdit
+	gen.pendingEdit = nil
+	gen.PhaseName = name
+	gen.SaveSnapshot()
+	return true
+}
+
+// RecomputeLakes は手で描いた後に湖の判定 (PhaseLakesFinal と同じ) をやり直し、湖かどうかが変わったタイルの数を返す
+// 記録していない手描きがあれば、それと合わせて1つの段として History に記録する (どちらもなければ何も記録しない)
+func (gen *World2Generator) RecomputeLakes() int {
+	w, h := gen.World2.Width, gen.World2.Height
+	was := make([]bool, w*h)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			was[y*w+x] = gen.World2.Tiles[x][y].IsLake
+		}
+	}
+	counts := gen.markLakes(w, h)
+	changed := 0
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if gen.World2.Tiles[x][y].IsLake != was[y*w+x] {
+				if gen.pendingEdit == nil {
+					gen.pendingEdit = make(map[int]bool)
+				}
+				gen.pendingEdit[y*w+x] = true
+				changed++
+			}
+		}
+	}
+	if len(gen.pendingEdit) == 0 {
+		return 0
+	}
+	// 集計は記録する段に含めるので、段名を先に変えて集計を書き換えてから記録する
+	gen.PhaseName = "Edit: Lakes"
+	gen.setStatsLine("Soil:", fmt.Sprintf("Soil:%d Cliff:%d", counts[W2TileSoil], counts[W2TileCliff]))
+	gen.setStatsLine("Lake:", fmt.Sprintf("Lake:%d Shlw:%d", counts[-1], counts[W2TileShallow]))
+	gen.CommitEdit(gen.PhaseName)
+	return changed
+}
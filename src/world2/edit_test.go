@@ -0,0 +1,158 @@
+// filename: world2/edit_test.go
+package world2
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// 筆の形ごとに塗る範囲が決まり、外周の固定海は塗らず、記録した手描きは Undo と Redo で行き来できること
+func TestPaintUndoRedo(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = 40, 40
+	gen := newTestGenerator(t, cfg, 4)
+	gen.Run()
+	before := tileBytes(gen.World2)
+	steps := len(gen.History)
+
+	if n := gen.Paint(1, 1, Brush{Type: W2TileSoil, Size: 3}); n != 0 {
+		t.Errorf("painted %d tiles on the border", n)
+	}
+	corner := gen.World2.Tiles[8][8]
+	gen.Paint(20, 20, Brush{Type: W2TileCliff, Size: 2})
+	gen.Paint(10, 10, Brush{Type: W2TileCliff, Size: 2, Shape: BrushRound})
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 40; y++ {
+			want := (x >= 18 && x <= 22 && y >= 18 && y <= 22) ||
+				(x-10)*(x-10)+(y-10)*(y-10) <= 6
+			if got := gen.World2.Tiles[x][y].Type == W2TileCliff; want && !got {
+				t.Fatalf("(%d,%d) not painted", x, y)
+			}
+		}
+	}
+	if gen.World2.Tiles[8][8] != corner {
+		t.Errorf("round brush painted the corner (8,8)")
+	}
+	if !gen.CommitEdit("Edit: Cliff") || gen.CommitEdit("Edit: nothing") {
+		t.Fatalf("commit: want exactly one recorded edit")
+	}
+	if len(gen.History) != steps+1 || !gen.IsFinished {
+		t.Fatalf("history %d (was %d), finished %v", len(gen.History), steps, gen.IsFinished)
+	}
+	after := tileBytes(gen.World2)
+
+	gen.UndoStep()
+	if !bytes.Equal(tileBytes(gen.World2), before) {
+		t.Errorf("undo does not restore the generated map")
+	}
+	gen.RedoStep()
+	if !bytes.Equal(tileBytes(gen.World2), after) || gen.PhaseName != "Edit: Cliff" {
+		t.Errorf("redo does not restore the edit (phase %q)", gen.PhaseName)
+	}
+
+	// 記録前の手描きは Undo でタイルとともに消える
+	gen.Paint(20, 20, Brush{Type: W2TileSoil})
+	gen.UndoStep()
+	if gen.CommitEdit("Edit: stale") {
+		t.Errorf("edit painted before undo was recorded")
+	}
+}
+
+// 塗りつぶしは同じ種類・生成元の4方向のつながりだけを塗ること
+func TestPaintFill(t *testing.T) {
+	gen := newTestGenerator(t, DefaultConfig(), 1)
+	m := newPathTestMap([]string{
+		"~~~~~~~~~~~",
+		"~~~~~~~~~~~",
+		"~~~~~~~~~~~",
+		"~~~##.#~~~~",
+		"~~~#.##~~~~",
+		"~~~~~~~~~~~",
+		"~~~~~~~~~~~",
+		"~~~~~~~~~~~",
+	})
+	gen.World2 = m
+	if n := gen.Paint(3, 3, Brush{Type: W2TileShallow, Shape: BrushFill}); n != 3 {
+		t.Errorf("fill painted %d tiles, want 3", n)
+	}
+	if m.Tiles[6][3].Type != W2TileSoil || m.Tiles[5][4].Type != W2TileSoil {
+		t.Errorf("fill crossed the sea into another landmass")
+	}
+}
+
+// 陸地で囲んだ海は、湖の判定をやり直すと湖になり、Undo で戻ること
+func TestRecomputeLakes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = 40, 40
+	gen := newTestGenerator(t, cfg, 2)
+	gen.Run()
+	gen.Paint(20, 20, Brush{Type: W2TileSoil, Size: 8})
+	gen.CommitEdit("Edit: Soil")
+	soil := tileBytes(gen.World2)
+
+	stats := append([]string(nil), gen.World2.StatsInfo...)
+	gen.Paint(20, 20, Brush{Type: W2TileVariableOcean, Size: 3})
+	if n := gen.RecomputeLakes(); n != 49 {
+		t.Errorf("%d tiles became lakes, want 49", n)
+	}
+	if !gen.World2.Tiles[20][20].IsLake || !gen.World2.Tiles[17][23].IsLake || gen.PhaseName != "Edit: Lakes" {
+		t.Errorf("pool is not a lake (phase %q)", gen.PhaseName)
+	}
+	if err := checkLakes(gen.World2); err != nil {
+		t.Error(err)
+	}
+	// 記録した段は書き換えた後の集計を持ち、Undo で前の集計に戻る
+	if rec := gen.History[len(gen.History)-1].StatsInfo; !reflect.DeepEqual(rec, gen.World2.StatsInfo) || reflect.DeepEqual(rec, stats) {
+		t.Errorf("recorded stats %q, current %q", rec, gen.World2.StatsInfo)
+	}
+	gen.UndoStep()
+	if !bytes.Equal(tileBytes(gen.World2), soil) {
+		t.Errorf("undo does not remove both the pool and the lake flags")
+	}
+	if !reflect.DeepEqual(gen.World2.StatsInfo, stats) {
+		t.Errorf("undo stats %q, want %q", gen.World2.StatsInfo, stats)
+	}
+	if n := gen.RecomputeLakes(); n != 0 || gen.PhaseName != "Edit: Soil" {
+		t.Errorf("recompute without changes: %d tiles, phase %q", n, gen.PhaseName)
+	}
+}
+
+// 首都を塗るとその枠が NoCapital になり (ほかの首都と国の対応は変わらない)、保存しても Undo しても崩れないこと
+func TestPaintDropsCapital(t *testing.T) {
+	gen := newTestGenerator(t, DefaultConfig(), 6)
+	gen.Run()
+	caps := append([]Point(nil), gen.World2.Capitals...)
+	if len(caps) < 2 {
+		t.Fatalf("test map has %d capitals", len(caps))
+	}
+	c := caps[0]
+	gen.Paint(c.X, c.Y, Brush{Type: W2TileVariableOcean})
+	want := append([]Point{NoCapital}, caps[1:]...)
+	if !reflect.DeepEqual(gen.World2.Capitals, want) {
+		t.Fatalf("capitals after painting %v: %v", c, gen.World2.Capitals)
+	}
+	for i, c := range gen.World2.Capitals[1:] {
+		if n := gen.World2.Tiles[c.X][c.Y].Nation; int(n) != i+2 {
+			t.Errorf("capital %v is in nation %d, want %d", c, n, i+2)
+		}
+	}
+	gen.CommitEdit("Edit: Ocean")
+
+	var buf bytes.Buffer
+	if err := gen.SaveData().WriteBinary(&buf); err != nil {
+		t.Fatalf("WriteBinary: %v", err)
+	}
+	d, err := ReadSave(&buf)
+	if err != nil {
+		t.Fatalf("ReadSave: %v", err)
+	}
+	if !reflect.DeepEqual(d.Capitals, want) {
+		t.Errorf("saved capitals %v, want %v", d.Capitals, want)
+	}
+
+	gen.UndoStep()
+	if !reflect.DeepEqual(gen.World2.Capitals, caps) {
+		t.Errorf("undo capitals %v, want %v", gen.World2.Capitals, caps)
+	}
+}
@@ -0,0 +1,101 @@
+// filename: world2/elevation_test.go
+package world2
+
+import (
+	"bytes"
+	"testing"
+)
+
+// 陸地は 1..ElevationMax、海と湖は 0 以下で、崖は上乗せ分より高く、内陸ほど平均して高いこと
+func TestElevationLayer(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		m, err := Generate(DefaultConfig(), seed)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		coast := distanceField(m.Tiles, m.Width, m.Height,
+			func(t World2Tile) bool { return !isLandType(t.Type) },
+			func(t World2Tile) bool { return isLandType(t.Type) })
+
+		var coastSum, coastN, inlandSum, inlandN int
+		for x := 0; x < m.Width; x++ {
+			for y := 0; y < m.Height; y++ {
+				tile := m.Tiles[x][y]
+				e := int(tile.Elevation)
+				if !isLandType(tile.Type) {
+					if e > 0 || e < -SeaDepthMax {
+						t.Fatalf("seed %d: water (%d,%d) has elevation %d", seed, x, y, e)
+					}
+					continue
+				}
+				if e < 1 || e > ElevationMax {
+					t.Fatalf("seed %d: land (%d,%d) has elevation %d", seed, x, y, e)
+				}
+				if tile.Type == W2TileCliff && e < elevCliffBonus {
+					t.Errorf("seed %d: cliff (%d,%d) elevation %d below cliff bonus", seed, x, y, e)
+				}
+				if tile.Type == W2TileSoil && coast[x][y] == 1 {
+					coastSum += e
+					coastN++
+				} else if coast[x][y] >= 4 {
+					inlandSum += e
+					inlandN++
+				}
+			}
+		}
+		if coastN == 0 || inlandN == 0 {
+			t.Fatalf("seed %d: no coast or inland tiles", seed)
+		}
+		if inlandSum/inlandN <= coastSum/coastN {
+			t.Errorf("seed %d: inland avg %d not above coast avg %d", seed, inlandSum/inlandN, coastSum/coastN)
+		}
+	}
+}
+
+// 標高は保存・読み込みで保たれ、標高のない version 1 の保存データも読み込めること
+func TestElevationSaveCompat(t *testing.T) {
+	m, err := Generate(DefaultConfig(), 4)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	d := NewSaveData(m, DefaultConfig(), 4)
+	var buf bytes.Buffer
+	if err := d.WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSave(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := got.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tileBytes(m), tileBytes(loaded)) {
+		t.Errorf("elevation lost in binary round trip")
+	}
+
+	for _, write := range []func(*SaveData, *bytes.Buffer) error{
+		func(d *SaveData, b *bytes.Buffer) error { return d.WriteJSON(b) },
+		func(d *SaveData, b *bytes.Buffer) error { return d.WriteBinary(b) },
+	} {
+		v1 := *d
+		v1.Version, v1.Elevation, v1.River, v1.Biome, v1.Nation, v1.Capitals = 1, nil, nil, nil, nil, nil
+		v1.Settlement, v1.Road = nil, nil
+		buf.Reset()
+		if err := write(&v1, &buf); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadSave(&buf)
+		if err != nil {
+			t.Fatalf("version 1: %v", err)
+		}
+		old, err := got.Map()
+		if err != nil {
+			t.Fatalf("version 1: %v", err)
+		}
+		if old.Tiles[m.Width/2][m.Height/2].Type != m.Tiles[m.Width/2][m.Height/2].Type || old.Tiles[m.Width/2][m.Height/2].Elevation != 0 {
+			t.Errorf("version 1 tiles not restored without elevation")
+		}
+	}
+}
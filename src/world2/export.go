@@ -0,0 +1,123 @@
+// filename: world2/export.go
+package world2
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// renderTiles は TileColor のパレットで1タイルを scale x scale ピクセルとして描画する
+func renderTiles(tiles [][]World2Tile, w, h, scale int, newSoils map[int]bool) *image.RGBA {
+	if scale < 1 {
+		scale = 1
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w*scale, h*scale))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			c := TileColor(tiles[x][y], newSoils[y*w+x])
+			draw.Draw(img, image.Rect(x*scale, y*scale, (x+1)*scale, (y+1)*scale), &image.Uniform{c}, image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+// Image はマップ全体を画像化する
+func (m *WorldMap2) Image(scale int) *image.RGBA {
+	return renderTiles(m.Tiles, m.Width, m.Height, scale, nil)
+}
+
+// SnapshotImage は History[i] を画像化する (そのステップで変化したタイルは明るく表示)
+func (gen *World2Generator) SnapshotImage(i, scale int) *image.RGBA {
+	return renderTiles(gen.SnapshotTiles(i), gen.Config.W, gen.Config.H, scale, gen.History[i].NewSoils)
+}
+
+// WritePNG はマップを PNG として書き出す
+func (m *WorldMap2) WritePNG(w io.Writer, scale int) error {
+	return png.Encode(w, m.Image(scale))
+}
+
+func writePNGFile(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, img)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// SavePNG はマップを PNG ファイルに保存する
+func (m *WorldMap2) SavePNG(path string, scale int) error {
+	return writePNGFile(path, m.Image(scale))
+}
+
+// ExportHistoryPNGs は History の各スナップショットを dir/step_00.png, step_01.png ... として保存する
+func (gen *World2Generator) ExportHistoryPNGs(dir string, scale int) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(gen.History))
+	err := gen.EachSnapshot(func(i int, s GenSnapshot, tiles [][]World2Tile) error {
+		path := filepath.Join(dir, fmt.Sprintf("step_%02d.png", i))
+		if err := writePNGFile(path, renderTiles(tiles, gen.Config.W, gen.Config.H, scale, s.NewSoils)); err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	return paths, err
+}
+
+// ContactSheet は History を cols 列に並べ、各コマの上に番号と PhaseName を書いた1枚の画像にする
+func (gen *World2Generator) ContactSheet(cols, scale int) *image.RGBA {
+	if cols < 1 {
+		cols = 1
+	}
+	if len(gen.History) == 0 {
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	const labelH, pad = 16, 4
+	cellW := gen.Config.W * scale
+	cellH := gen.Config.H*scale + labelH
+	rows := (len(gen.History) + cols - 1) / cols
+
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*(cellW+pad)+pad, rows*(cellH+pad)+pad))
+	draw.Draw(sheet, sheet.Bounds(), &image.Uniform{color.RGBA{10, 10, 30, 255}}, image.Point{}, draw.Src)
+
+	drawer := &font.Drawer{Dst: sheet, Src: image.White, Face: basicfont.Face7x13}
+	gen.EachSnapshot(func(i int, s GenSnapshot, tiles [][]World2Tile) error {
+		ox := pad + (i%cols)*(cellW+pad)
+		oy := pad + (i/cols)*(cellH+pad)
+		img := renderTiles(tiles, gen.Config.W, gen.Config.H, scale, s.NewSoils)
+		draw.Draw(sheet, image.Rect(ox, oy+labelH, ox+cellW, oy+cellH), img, image.Point{}, draw.Src)
+
+		label := fmt.Sprintf("%02d %s", i, s.PhaseName)
+		if maxChars := cellW / 7; len(label) > maxChars && maxChars > 0 {
+			label = label[:maxChars]
+		}
+		drawer.Dot = fixed.P(ox, oy+12)
+		drawer.DrawString(label)
+		return nil
+	})
+	return sheet
+}
+
+// SaveContactSheet は ContactSheet を PNG ファイルに保存する
+func (gen *World2Generator) SaveContactSheet(path string, cols, scale int) error {
+	return writePNGFile(path, gen.ContactSheet(cols, scale))
+}
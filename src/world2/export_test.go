@@ -0,0 +1,53 @@
+// filename: world2/export_test.go
+package world2
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+// PNG のサイズと各タイルの色が TileColor のパレットと一致すること
+func TestWritePNGUsesTilePalette(t *testing.T) {
+	m, err := Generate(DefaultConfig(), 5)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	const scale = 3
+	var buf bytes.Buffer
+	if err := m.WritePNG(&buf, scale); err != nil {
+		t.Fatalf("WritePNG: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != m.Width*scale || b.Dy() != m.Height*scale {
+		t.Fatalf("image size %dx%d, want %dx%d", b.Dx(), b.Dy(), m.Width*scale, m.Height*scale)
+	}
+	for x := 0; x < m.Width; x += 7 {
+		for y := 0; y < m.Height; y += 5 {
+			want := TileColor(m.Tiles[x][y], false)
+			r, g, b, _ := img.At(x*scale+1, y*scale+1).RGBA()
+			if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+				t.Fatalf("pixel at tile (%d,%d) does not match palette", x, y)
+			}
+		}
+	}
+}
+
+func TestContactSheetHasOneCellPerSnapshot(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = 40, 40
+	gen := newTestGenerator(t, cfg, 1)
+	gen.Run()
+
+	const cols, scale = 4, 1
+	sheet := gen.ContactSheet(cols, scale)
+	rows := (len(gen.History) + cols - 1) / cols
+	// セル = 40px + ラベル16px、余白4px
+	wantW, wantH := cols*(40+4)+4, rows*(40+16+4)+4
+	if b := sheet.Bounds(); b.Dx() != wantW || b.Dy() != wantH {
+		t.Fatalf("sheet size %dx%d, want %dx%d", b.Dx(), b.Dy(), wantW, wantH)
+	}
+}
@@ -0,0 +1,334 @@
+// filename: world2/generator.go
+package world2
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Validate はマップサイズが生成可能な範囲にあるかを確認する
+func (c GenConfig) Validate() error {
+	if c.W < MinSize || c.H < MinSize {
+		return errors.New("Size >= 40x40")
+	}
+	if c.W > MaxSize || c.H > MaxSize {
+		return errors.New("Size <= 500x500")
+	}
+	if c.MainType < MinMaskType || c.MainType > MaxMaskType || c.SubType < MinMaskType || c.SubType > MaxMaskType {
+		return errors.New("Map Type: 1-9")
+	}
+	if c.Ratio < 0 || c.Ratio > 10 {
+		return errors.New("Ratio: 0-10")
+	}
+	if c.RiverCount < 0 || c.RiverMinLen < 0 {
+		return errors.New("Rivers: Count, MinLen >= 0")
+	}
+	if c.NationCount < 0 || c.NationCount > MaxNations {
+		return fmt.Errorf("Nations: 0-%d", MaxNations)
+	}
+	if c.TownCount < 0 {
+		return errors.New("Settlements: Towns >= 0")
+	}
+	if _, err := BuildPhases(c); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ClassicOnly は合成後のマスクが Type 1 (クラシック) のみで決まるかを返す
+func (c GenConfig) ClassicOnly() bool {
+	mainClassic := c.MainType == MaskClassic || c.Ratio == 0
+	subClassic := c.SubType == MaskClassic || c.Ratio == 10
+	return mainClassic && subClassic
+}
+
+// UsesMask は合成マスクに typeID の形状が寄与しているかを返す
+func (c GenConfig) UsesMask(typeID int) bool {
+	return (c.MainType == typeID && c.Ratio > 0) || (c.SubType == typeID && c.Ratio < 10)
+}
+
+// NewWorldMap2 は外周3マスを固定海、それ以外を可変海で埋めたマップを作成する
+func NewWorldMap2(w, h int) *WorldMap2 {
+	m := &WorldMap2{
+		Width:     w,
+		Height:    h,
+		Tiles:     make([][]World2Tile, w),
+		PinkRects: []Rect{},
+	}
+	for x := 0; x < w; x++ {
+		m.Tiles[x] = make([]World2Tile, h)
+		for y := 0; y < h; y++ {
+			if x < 3 || x >= w-3 || y < 3 || y >= h-3 {
+				m.Tiles[x][y] = World2Tile{Type: W2TileFixedOcean}
+			} else {
+				m.Tiles[x][y] = World2Tile{Type: W2TileVariableOcean}
+			}
+		}
+	}
+	return m
+}
+
+// NewGenerator は GenConfig とシードから生成器を作成し、Phase_Init のスナップショットを保存する
+func NewGenerator(cfg GenConfig, seed int64) (*World2Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	phases, err := BuildPhases(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := &World2Generator{
+		CurrentStep: 0,
+		IsFinished:  false,
+		PhaseName:   "Start (Fixed Ocean)",
+		History:     []GenSnapshot{},
+		Phases:      phases,
+		World2:      NewWorldMap2(cfg.W, cfg.H),
+		Rng:         rand.New(rand.NewSource(seed)),
+		StartSeed:   seed,
+		CurrentSeed: seed,
+		Config:      cfg,
+		Multiplier:  cfg.CliffInit,
+		Excluded:    make(map[int]bool),
+		NewSoils:    make(map[int]bool),
+	}
+	gen.skipInactive()
+	gen.SaveSnapshot()
+	return gen, nil
+}
+
+// Generate は全フェーズを実行し、完成したマップを返す
+func Generate(cfg GenConfig, seed int64) (*WorldMap2, error) {
+	gen, err := NewGenerator(cfg, seed)
+	if err != nil {
+		return nil, err
+	}
+	gen.Run()
+	return gen.World2, nil
+}
+
+// Run は IsFinished になるまで NextStep を繰り返す
+func (gen *World2Generator) Run() {
+	for !gen.IsFinished {
+		gen.NextStep()
+	}
+}
+
+// SaveSnapshot は現在の状態を History に追加する (タイルは差分かキーフレームで記録)
+func (gen *World2Generator) SaveSnapshot() {
+	newSoilsCopy := make(map[int]bool)
+	for k, v := range gen.NewSoils { newSoilsCopy[k] = v }
+
+	exCopy := make(map[int]bool)
+	for k, v := range gen.Excluded { exCopy[k] = v }
+
+	pinkCopy := make([]Rect, len(gen.World2.PinkRects))
+	copy(pinkCopy, gen.World2.PinkRects)
+
+	capitalsCopy := append([]Point(nil), gen.World2.Capitals...)
+
+	walkersCopy := make([]struct{x, y int}, len(gen.Walkers))
+	copy(walkersCopy, gen.Walkers)
+
+	snap := GenSnapshot{
+		PhaseName: gen.PhaseName,
+		StepID:    gen.CurrentStep,
+		NewSoils:  newSoilsCopy,
+		Excluded:  exCopy,
+		Multiplier: gen.Multiplier,
+		PinkRects: pinkCopy,
+		Capitals:  capitalsCopy,
+		StatsInfo: append([]string(nil), gen.World2.StatsInfo...),
+		Walkers:   walkersCopy,
+		CurrentSoilCount: gen.CurrentSoilCount,
+		CurrentSeed: gen.CurrentSeed,
+		CliffStreak: gen.CliffStreak,
+		ShallowStreak: gen.ShallowStreak,
+		TotalRoute1Dist: gen.TotalRoute1Dist,
+		Route1: append([]Route1Info(nil), gen.Route1...),
+		Route2Needed: gen.Route2Needed,
+		LastTargetSoil: gen.LastTargetSoil,
+		MaskMain: gen.MaskMain,
+		MaskSub: gen.MaskSub,
+		FinalMask: gen.FinalMask,
+		TargetSoilCount: gen.TargetSoilCount,
+		PhaseIndex: gen.PhaseIndex,
+	}
+	gen.recordTiles(&snap)
+	gen.History = append(gen.History, snap)
+}
+
+// UndoStep は最後のスナップショットを Redo に移し、1つ前の状態に戻す
+func (gen *World2Generator) UndoStep() {
+	if len(gen.History) > 1 {
+		gen.JumpTo(len(gen.History) - 2)
+	}
+}
+
+// RedoStep は UndoStep で戻したスナップショットを1つ再適用する
+func (gen *World2Generator) RedoStep() {
+	if len(gen.Redo) > 0 {
+		gen.JumpTo(len(gen.History))
+	}
+}
+
+// TimelineLen は History と Redo を合わせたスナップショット数を返す
+func (gen *World2Generator) TimelineLen() int {
+	return len(gen.History) + len(gen.Redo)
+}
+
+// TimelineSnapshot は History と Redo を通した i 番目のスナップショットを返す
+func (gen *World2Generator) TimelineSnapshot(i int) GenSnapshot {
+	if i < len(gen.History) {
+		return gen.History[i]
+	}
+	return gen.Redo[i-len(gen.History)]
+}
+
+// JumpTo は History と Redo を通した i 番目のスナップショットの状態に移る
+// i より後ろは Redo に残るので、RedoStep や JumpTo で再び進められる
+func (gen *World2Generator) JumpTo(i int) {
+	if i < 0 || i >= gen.TimelineLen() || i == len(gen.History)-1 {
+		return
+	}
+	if i < len(gen.History) {
+		popped := gen.History[i+1:]
+		gen.Redo = append(append([]GenSnapshot(nil), popped...), gen.Redo...)
+		gen.History = gen.History[:i+1]
+	} else {
+		n := i + 1 - len(gen.History)
+		gen.History = append(gen.History, gen.Redo[:n]...)
+		gen.Redo = append([]GenSnapshot(nil), gen.Redo[n:]...)
+	}
+	gen.restoreSnapshot()
+}
+
+// Branch は現在のスナップショットから先を新しいシードでやり直すため、次の段の乱数を seed に差し替える
+// Redo は捨てる (以降の段は NextStep で新しく生成される)
+// 最後の段まで終わっていてやり直す段がなければ、何も変えずに偽を返す
+func (gen *World2Generator) Branch(seed int64) bool {
+	if gen.IsFinished {
+		return false
+	}
+	gen.Redo = nil
+	gen.CurrentSeed = seed
+	gen.Rng.Seed(seed)
+	gen.History[len(gen.History)-1].CurrentSeed = seed
+	return true
+}
+
+// restoreSnapshot は History 末尾のスナップショットの状態を生成器に復元する
+func (gen *World2Generator) restoreSnapshot() {
+	last := gen.History[len(gen.History)-1]
+
+	gen.restoreTiles(len(gen.History)-1, gen.histTip)
+	for x := 0; x < gen.Config.W; x++ {
+		copy(gen.World2.Tiles[x], gen.histTip[x])
+	}
+
+	gen.PhaseName = last.PhaseName
+	gen.CurrentStep = last.StepID
+	gen.PhaseIndex = last.PhaseIndex
+	gen.IsFinished = gen.PhaseIndex >= len(gen.Phases)
+
+	gen.NewSoils = make(map[int]bool)
+	for k, v := range last.NewSoils { gen.NewSoils[k] = v }
+	gen.pendingEdit = nil // 記録前の手描きはタイルとともに捨てる
+
+	gen.Excluded = make(map[int]bool)
+	for k, v := range last.Excluded { gen.Excluded[k] = v }
+
+	gen.World2.PinkRects = make([]Rect, len(last.PinkRects))
+	copy(gen.World2.PinkRects, last.PinkRects)
+	gen.World2.Capitals = append([]Point(nil), last.Capitals...)
+	gen.World2.StatsInfo = append([]string(nil), last.StatsInfo...)
+
+	gen.Walkers = make([]struct{x, y int}, len(last.Walkers))
+	copy(gen.Walkers, last.Walkers)
+
+	gen.CurrentSoilCount = last.CurrentSoilCount
+	gen.Multiplier = last.Multiplier
+	gen.CurrentSeed = last.CurrentSeed
+	gen.Rng.Seed(gen.CurrentSeed)
+	gen.CliffStreak = last.CliffStreak
+	gen.ShallowStreak = last.ShallowStreak
+	gen.TotalRoute1Dist = last.TotalRoute1Dist
+	gen.Route1 = append([]Route1Info(nil), last.Route1...)
+	gen.Route2Needed = last.Route2Needed
+	gen.LastTargetSoil = last.LastTargetSoil
+	gen.MaskMain, gen.MaskSub, gen.FinalMask = last.MaskMain, last.MaskSub, last.FinalMask
+	gen.TargetSoilCount = last.TargetSoilCount
+}
+
+// NextStep は次の段を1つ実行し、スナップショットを保存する
+// Redo が残っている場合は同じ結果になるので、生成し直さずに再適用する
+func (gen *World2Generator) NextStep() {
+	if len(gen.Redo) > 0 {
+		gen.RedoStep()
+		return
+	}
+	gen.skipInactive()
+	if gen.IsFinished { return }
+
+	w, h := gen.Config.W, gen.Config.H
+	p := gen.Phases[gen.PhaseIndex]
+
+	gen.Rng.Seed(gen.CurrentSeed)
+	rng := gen.Rng
+
+	gen.NewSoils = make(map[int]bool)
+	gen.World2.PinkRects = []Rect{}
+
+	gen.CurrentStep = p.ID
+	gen.PhaseName = p.Name
+	p.Run(gen, w, h, rng)
+	if gen.aborted {
+		// 途中で打ち切った段は記録せず、段の前のスナップショットに戻す (続きはこの段の最初からやり直す)
+		gen.aborted = false
+		gen.restoreSnapshot()
+		return
+	}
+	// 番号は段の ID から付ける (各段は番号なしの名前だけを設定する)
+	gen.PhaseName = fmt.Sprintf("%d. %s", p.ID, gen.PhaseName)
+
+	gen.PhaseIndex++
+	gen.CurrentSeed = gen.Rng.Int63() // Save next seed
+	gen.skipInactive()
+	gen.SaveSnapshot()
+}
+
+// setStatsLine は湖の段で作った集計 (StatsInfo) の先頭を現在の段名にし、prefix で始まる行を line に置き換える
+// (なければ末尾に加える。やり直した段が同じ行を重ねて追加しないようにするため)
+func (gen *World2Generator) setStatsLine(prefix, line string) {
+	stats := []string{fmt.Sprintf("Phase: %s", gen.PhaseName)}
+	for i, s := range gen.World2.StatsInfo {
+		if i > 0 && !strings.HasPrefix(s, prefix) {
+			stats = append(stats, s)
+		}
+	}
+	gen.World2.StatsInfo = append(stats, line)
+}
+
+// noteSafetyCap は段の中の繰り返しが安全のための上限に達して打ち切られたことを SafetyCapHits に記録する
+func (gen *World2Generator) noteSafetyCap(loop string) {
+	gen.SafetyCapHits = append(gen.SafetyCapHits, fmt.Sprintf("%d %s", gen.CurrentStep, loop))
+}
+
+// skipInactive は無効な段と Skip 条件に当たる段を飛ばし、CurrentStep を次に実行する段の ID に合わせる
+// 残りの段がなければ IsFinished にする
+func (gen *World2Generator) skipInactive() {
+	for gen.PhaseIndex < len(gen.Phases) {
+		if p := gen.Phases[gen.PhaseIndex]; p.active(gen) {
+			gen.CurrentStep = p.ID
+			return
+		}
+		gen.PhaseIndex++
+	}
+	if !gen.IsFinished {
+		gen.IsFinished = true
+		gen.CurrentStep++
+	}
+}
@@ -0,0 +1,307 @@
+// filename: world2/generator_test.go
+package world2
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// tileBytes は Tiles を比較用のバイト列に変換する
+func tileBytes(m *WorldMap2) []byte {
+	var buf bytes.Buffer
+	for x := 0; x < m.Width; x++ {
+		for y := 0; y < m.Height; y++ {
+			t := m.Tiles[x][y]
+			lake := byte(0)
+			if t.IsLake {
+				lake = 1
+			}
+			buf.WriteByte(byte(t.Type))
+			buf.WriteByte(byte(t.Source))
+			buf.WriteByte(lake)
+			buf.WriteByte(t.Biome)
+			buf.WriteByte(t.Nation)
+			buf.WriteByte(t.Settlement)
+			if t.IsRoad {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+			if t.IsRiver {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+			buf.WriteByte(byte(t.Elevation))
+			buf.WriteByte(byte(t.Elevation >> 8))
+		}
+	}
+	return buf.Bytes()
+}
+
+func newTestGenerator(t *testing.T, cfg GenConfig, seed int64) *World2Generator {
+	t.Helper()
+	gen, err := NewGenerator(cfg, seed)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	return gen
+}
+
+// 同じシードと GenConfig からは全フェーズで同一の Tiles が得られること
+func TestSameSeedSameTilesEveryStep(t *testing.T) {
+	cfg := DefaultConfig()
+	for _, seed := range []int64{1, 42, 20251018, 9223372036854775807} {
+		a := newTestGenerator(t, cfg, seed)
+		b := newTestGenerator(t, cfg, seed)
+
+		for !a.IsFinished && a.CurrentStep <= Phase_Settlements {
+			step := a.CurrentStep
+			a.NextStep()
+			b.NextStep()
+			if a.PhaseName != b.PhaseName || a.CurrentStep != b.CurrentStep {
+				t.Fatalf("seed %d step %d: phase diverged (%q/%d vs %q/%d)", seed, step, a.PhaseName, a.CurrentStep, b.PhaseName, b.CurrentStep)
+			}
+			if !bytes.Equal(tileBytes(a.World2), tileBytes(b.World2)) {
+				t.Fatalf("seed %d step %d (%s): tiles differ", seed, step, a.PhaseName)
+			}
+		}
+		if !a.IsFinished {
+			t.Errorf("seed %d: generation did not finish (step %d)", seed, a.CurrentStep)
+		}
+	}
+}
+
+// 地殻変動 (段 4) で、それまでの土が PhaseName に出したずれの分だけ平行移動し、外周の固定海は残ること
+// 段 4 は土を 10% 足してからずらすので、重心はずれの分に数マスの誤差で一致する
+func TestTectonicShiftMovesSoil(t *testing.T) {
+	for _, seed := range []int64{1, 42, 20251018, 5, 6} {
+		gen := newTestGenerator(t, DefaultConfig(), seed)
+		for gen.CurrentStep < 4 {
+			gen.NextStep()
+		}
+		w, h := gen.World2.Width, gen.World2.Height
+		type pt struct{ x, y int }
+		var before []pt
+		var bx, by float64
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				if gen.World2.Tiles[x][y].Type == W2TileSoil {
+					before = append(before, pt{x, y})
+					bx += float64(x)
+					by += float64(y)
+				}
+			}
+		}
+		bx, by = bx/float64(len(before)), by/float64(len(before))
+
+		gen.NextStep()
+		var dx, dy int
+		i := strings.Index(gen.PhaseName, "Tectonic (")
+		if i < 0 {
+			t.Fatalf("seed %d: step 4 %q has no tectonic shift", seed, gen.PhaseName)
+		}
+		if _, err := fmt.Sscanf(gen.PhaseName[i:], "Tectonic (%d,%d)", &dx, &dy); err != nil {
+			t.Fatalf("seed %d: %q: %v", seed, gen.PhaseName, err)
+		}
+
+		for _, p := range before {
+			nx, ny := p.x+dx, p.y+dy
+			if nx >= 3 && nx < w-3 && ny >= 3 && ny < h-3 && gen.World2.Tiles[nx][ny].Type != W2TileSoil {
+				t.Fatalf("seed %d: soil at (%d,%d) did not move to (%d,%d)", seed, p.x, p.y, nx, ny)
+			}
+		}
+		var ax, ay, n float64
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				tile := gen.World2.Tiles[x][y]
+				if (x < 3 || x >= w-3 || y < 3 || y >= h-3) && tile.Type != W2TileFixedOcean {
+					t.Fatalf("seed %d: border tile (%d,%d) is type %d after the shift", seed, x, y, tile.Type)
+				}
+				if tile.Type == W2TileSoil {
+					ax += float64(x)
+					ay += float64(y)
+					n++
+				}
+			}
+		}
+		if mx, my := ax/n-bx, ay/n-by; math.Abs(mx-float64(dx)) > 5 || math.Abs(my-float64(dy)) > 5 {
+			t.Errorf("seed %d: soil centroid moved (%.1f,%.1f), shift (%d,%d)", seed, mx, my, dx, dy)
+		}
+	}
+}
+
+// Generate の結果と、途中で Undo してやり直した結果が一致すること
+func TestGenerateMatchesUndoReplay(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = 80, 60
+	const seed = 7
+
+	want, err := Generate(cfg, seed)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	gen := newTestGenerator(t, cfg, seed)
+	for gen.CurrentStep <= 5 {
+		gen.NextStep()
+	}
+	gen.UndoStep()
+	gen.UndoStep()
+	gen.Run()
+
+	if !bytes.Equal(tileBytes(want), tileBytes(gen.World2)) {
+		t.Fatalf("tiles after undo/replay differ from a straight run")
+	}
+}
+
+// シードが異なれば結果も変わること (シードが実際に使われていることの確認)
+func TestDifferentSeedDifferentTiles(t *testing.T) {
+	cfg := DefaultConfig()
+	a, err := Generate(cfg, 1)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	b, err := Generate(cfg, 2)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if bytes.Equal(tileBytes(a), tileBytes(b)) {
+		t.Fatalf("seeds 1 and 2 produced identical maps")
+	}
+}
+
+// soilSources は土タイルの Source ごとの数を返す
+func soilSources(m *WorldMap2) map[int]int {
+	counts := map[int]int{}
+	for x := 0; x < m.Width; x++ {
+		for y := 0; y < m.Height; y++ {
+			if m.Tiles[x][y].Type == W2TileSoil {
+				counts[m.Tiles[x][y].Source]++
+			}
+		}
+	}
+	return counts
+}
+
+// Ratio=10 では Main のみ、Ratio=0 では Sub のみの土になること
+func TestMaskBlendSourceAttribution(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MainType, cfg.SubType = MaskLeftCont, MaskRightCont
+	for _, tc := range []struct{ ratio, want int }{{10, SrcMain}, {0, SrcSub}} {
+		cfg.Ratio = tc.ratio
+		gen := newTestGenerator(t, cfg, 3)
+		for gen.CurrentStep <= Phase_SoilProgressEnd {
+			gen.NextStep()
+		}
+		c := soilSources(gen.World2)
+		if c[tc.want] == 0 || len(c) != 1 {
+			t.Errorf("Ratio %d: soil sources %v, want only %d", tc.ratio, c, tc.want)
+		}
+	}
+}
+
+// Main/Sub の寄与の大小で SrcMain/SrcSub/SrcMix が決まること
+func TestMaskSource(t *testing.T) {
+	gen := &World2Generator{
+		Config:   GenConfig{Ratio: 5},
+		MaskMain: [][]float64{{1, 1, 0, 0}},
+		MaskSub:  [][]float64{{0, 1, 1, 0}},
+	}
+	for y, want := range []int{SrcMain, SrcMix, SrcSub, SrcMain} {
+		if got := gen.MaskSource(0, y); got != want {
+			t.Errorf("MaskSource(0, %d) = %d, want %d", y, got, want)
+		}
+	}
+}
+
+func TestBlendMasks(t *testing.T) {
+	main := [][]float64{{1, 0}}
+	sub := [][]float64{{0, 1}}
+	got := BlendMasks(main, sub, 7)
+	if got[0][0] != 0.7 || got[0][1] < 0.299 || got[0][1] > 0.301 {
+		t.Fatalf("BlendMasks = %v", got)
+	}
+}
+
+// 同じシードでもマスクの種類ごとに陸地の分布が変わり、Type 2-9 の土はマスクの高い所に集まること
+// (地殻変動で陸地がずれる前の段 3 まで置いた土で、マスク値の平均を内側全体の平均と比べる)
+func TestMaskTypesShapeLand(t *testing.T) {
+	const seed = 11
+	seen := map[string]int{}
+	for mt := MinMaskType; mt <= MaxMaskType; mt++ {
+		cfg := DefaultConfig()
+		cfg.MainType, cfg.SubType, cfg.Ratio = mt, mt, 10
+		gen := newTestGenerator(t, cfg, seed)
+		for gen.CurrentStep < 4 {
+			gen.NextStep()
+		}
+		w, h := cfg.W, cfg.H
+		var inner, soil float64
+		var nInner, nSoil int
+		for x := 3; x < w-3; x++ {
+			for y := 3; y < h-3; y++ {
+				inner += gen.FinalMask[x][y]
+				nInner++
+				if gen.World2.Tiles[x][y].Type == W2TileSoil {
+					soil += gen.FinalMask[x][y]
+					nSoil++
+				}
+			}
+		}
+		if nSoil == 0 {
+			t.Fatalf("type %d: no soil placed", mt)
+		}
+		if avgSoil, avgInner := soil/float64(nSoil), inner/float64(nInner); mt != MaskClassic && avgSoil < avgInner+0.2 {
+			t.Errorf("type %d: soil mask avg %.2f not above map avg %.2f", mt, avgSoil, avgInner)
+		}
+
+		gen.Run()
+		land := make([]byte, 0, w*h)
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				if tt := gen.World2.Tiles[x][y].Type; tt == W2TileSoil || tt == W2TileCliff {
+					land = append(land, 1)
+				} else {
+					land = append(land, 0)
+				}
+			}
+		}
+		if prev, ok := seen[string(land)]; ok {
+			t.Errorf("types %d and %d produced the same land with seed %d", prev, mt, seed)
+		}
+		seen[string(land)] = mt
+	}
+}
+
+// マスクがクラシック一色でなければ、ウォーカーはマスク値 > 0.6 の地点から歩き始めること
+// (最初の土の段の目標を 0 にして、置いたばかりのウォーカーの位置を調べる)
+func TestWalkersSpawnOnHighMask(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		for mt := MinMaskType; mt <= MaxMaskType; mt++ {
+			cfg := DefaultConfig()
+			cfg.MainType, cfg.SubType, cfg.Ratio = mt, MaskClassic, 7
+			gen := newTestGenerator(t, cfg, seed)
+			for gen.CurrentStep < Phase_SoilStart {
+				gen.NextStep()
+			}
+			gen.TargetSoilCount = 0
+			gen.NextStep()
+			if len(gen.Walkers) == 0 {
+				t.Fatalf("seed %d type %d: no walkers", seed, mt)
+			}
+			for _, p := range gen.Walkers {
+				if cfg.ClassicOnly() {
+					if p.x < cfg.W/2-5 || p.x >= cfg.W/2+5 || p.y < cfg.H/2-5 || p.y >= cfg.H/2+5 {
+						t.Errorf("seed %d classic: walker at (%d,%d) away from the center", seed, p.x, p.y)
+					}
+				} else if gen.FinalMask[p.x][p.y] <= 0.6 {
+					t.Errorf("seed %d type %d: walker spawned at (%d,%d) with mask %.2f", seed, mt, p.x, p.y, gen.FinalMask[p.x][p.y])
+				}
+			}
+		}
+	}
+}
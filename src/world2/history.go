@@ -0,0 +1,131 @@
+// filename: world2/history.go
+package world2
+
+import (
+	"unsafe"
+)
+
+// HistoryKeyframeInterval 個ごとに History へタイル全体の複製 (キーフレーム) を置く
+// それ以外のスナップショットは直前からの差分 (Diff) だけを持つ
+const HistoryKeyframeInterval = 8
+
+// TileChange はスナップショット間で変化した1タイル (Idx = y*w+x)
+type TileChange struct {
+	Idx  int32
+	Tile World2Tile
+}
+
+func copyTiles(src [][]World2Tile) [][]World2Tile {
+	dst := make([][]World2Tile, len(src))
+	for x := range src {
+		dst[x] = append([]World2Tile(nil), src[x]...)
+	}
+	return dst
+}
+
+// diffTiles は prev から cur への変化を走査順 (y*w+x の昇順) で返す
+func diffTiles(prev, cur [][]World2Tile, w, h int) []TileChange {
+	var diff []TileChange
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if cur[x][y] != prev[x][y] {
+				diff = append(diff, TileChange{Idx: int32(y*w + x), Tile: cur[x][y]})
+			}
+		}
+	}
+	return diff
+}
+
+func applyDiff(tiles [][]World2Tile, diff []TileChange, w int) {
+	for _, c := range diff {
+		i := int(c.Idx)
+		tiles[i%w][i/w] = c.Tile
+	}
+}
+
+// recordTiles は現在のタイルを History に追加するスナップショットへ記録する
+// 一定間隔、または差分がキーフレームより大きくなる場合はキーフレームにする
+func (gen *World2Generator) recordTiles(snap *GenSnapshot) {
+	w, h := gen.Config.W, gen.Config.H
+	cur := gen.World2.Tiles
+	if gen.histTip != nil && len(gen.History)%HistoryKeyframeInterval != 0 {
+		diff := diffTiles(gen.histTip, cur, w, h)
+		if len(diff)*int(unsafe.Sizeof(TileChange{})) < w*h*int(unsafe.Sizeof(World2Tile{})) {
+			snap.Diff = diff
+			applyDiff(gen.histTip, diff, w)
+			return
+		}
+	}
+	snap.Tiles = copyTiles(cur)
+	gen.histTip = copyTiles(cur)
+}
+
+// restoreTiles は History[i] 時点のタイルを dst に書き込む (直前のキーフレームから差分を適用)
+func (gen *World2Generator) restoreTiles(i int, dst [][]World2Tile) {
+	k := i
+	for gen.History[k].Tiles == nil {
+		k--
+	}
+	for x := range dst {
+		copy(dst[x], gen.History[k].Tiles[x])
+	}
+	for j := k + 1; j <= i; j++ {
+		applyDiff(dst, gen.History[j].Diff, gen.Config.W)
+	}
+}
+
+// SnapshotTiles は History[i] 時点のタイルを複製して返す
+func (gen *World2Generator) SnapshotTiles(i int) [][]World2Tile {
+	w, h := gen.Config.W, gen.Config.H
+	tiles := make([][]World2Tile, w)
+	for x := range tiles {
+		tiles[x] = make([]World2Tile, h)
+	}
+	gen.restoreTiles(i, tiles)
+	return tiles
+}
+
+// EachSnapshot は History を先頭から順に、その時点のタイルと一緒に fn へ渡す
+// tiles は呼び出しごとに書き換えられるので、保持する場合は複製すること
+func (gen *World2Generator) EachSnapshot(fn func(i int, s GenSnapshot, tiles [][]World2Tile) error) error {
+	if len(gen.History) == 0 {
+		return nil
+	}
+	tiles := gen.SnapshotTiles(0)
+	for i, s := range gen.History {
+		if s.Tiles != nil {
+			for x := range tiles {
+				copy(tiles[x], s.Tiles[x])
+			}
+		} else {
+			applyDiff(tiles, s.Diff, gen.Config.W)
+		}
+		if err := fn(i, s, tiles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HistoryStats は History の概算メモリ量 (バイト) とキーフレーム数を返す
+func (gen *World2Generator) HistoryStats() (bytes, keyframes int) {
+	tileSize := int(unsafe.Sizeof(World2Tile{}))
+	changeSize := int(unsafe.Sizeof(TileChange{}))
+	// map は1要素あたりキーと値に加えておおよそ同程度の管理領域を持つ
+	const mapEntrySize = 2 * (int(unsafe.Sizeof(int(0))) + 1)
+	if gen.histTip != nil {
+		bytes += gen.Config.W * gen.Config.H * tileSize
+	}
+	for _, s := range gen.History {
+		if s.Tiles != nil {
+			bytes += gen.Config.W * gen.Config.H * tileSize
+			keyframes++
+		}
+		bytes += len(s.Diff) * changeSize
+		bytes += (len(s.NewSoils) + len(s.Excluded)) * mapEntrySize
+		bytes += len(s.PinkRects)*int(unsafe.Sizeof(Rect{})) + len(s.Route1)*int(unsafe.Sizeof(Route1Info{}))
+		bytes += len(s.Walkers) * int(unsafe.Sizeof(struct{ x, y int }{}))
+		bytes += int(unsafe.Sizeof(s))
+	}
+	return bytes, keyframes
+}
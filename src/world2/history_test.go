@@ -0,0 +1,180 @@
+// filename: world2/history_test.go
+package world2
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// runRecording は1段ずつ生成し、各スナップショット時点のタイルを丸ごと複製して返す
+func runRecording(t *testing.T, cfg GenConfig, seed int64) (*World2Generator, [][][]World2Tile) {
+	t.Helper()
+	gen := newTestGenerator(t, cfg, seed)
+	frames := [][][]World2Tile{copyTiles(gen.World2.Tiles)}
+	for !gen.IsFinished {
+		gen.NextStep()
+		frames = append(frames, copyTiles(gen.World2.Tiles))
+	}
+	if len(frames) != len(gen.History) {
+		t.Fatalf("%d frames for %d snapshots", len(frames), len(gen.History))
+	}
+	return gen, frames
+}
+
+func sameTiles(a, b [][]World2Tile) bool {
+	for x := range a {
+		for y := range a[x] {
+			if a[x][y] != b[x][y] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 差分とキーフレームから、どのスナップショットのタイルも元通りに復元できること
+func TestHistoryRestoresEverySnapshot(t *testing.T) {
+	gen, frames := runRecording(t, DefaultConfig(), 3)
+	for i := range frames {
+		if !sameTiles(gen.SnapshotTiles(i), frames[i]) {
+			t.Fatalf("snapshot %d (%s) differs", i, gen.History[i].PhaseName)
+		}
+	}
+	err := gen.EachSnapshot(func(i int, s GenSnapshot, tiles [][]World2Tile) error {
+		if !sameTiles(tiles, frames[i]) {
+			t.Fatalf("EachSnapshot %d differs", i)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Undo で1段ずつ戻しても各段のタイルと一致し、同じシードで再実行すると同じ結果になること
+func TestUndoWithDiffHistory(t *testing.T) {
+	gen, frames := runRecording(t, DefaultConfig(), 4)
+	for i := len(frames) - 2; i >= 0; i-- {
+		gen.UndoStep()
+		if len(gen.History) != i+1 || !sameTiles(gen.World2.Tiles, frames[i]) {
+			t.Fatalf("after undo to %d: tiles differ", i)
+		}
+	}
+	gen.Run()
+	if !sameTiles(gen.World2.Tiles, frames[len(frames)-1]) {
+		t.Errorf("replay after full undo differs")
+	}
+	for i := range frames {
+		if !sameTiles(gen.SnapshotTiles(i), frames[i]) {
+			t.Fatalf("replayed snapshot %d differs", i)
+		}
+	}
+}
+
+// 大きなマップでも History は全スナップショットを丸ごと複製した場合より十分小さいこと
+func TestHistoryIsSmallerThanFullCopies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("large map")
+	}
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = MaxSize, MaxSize
+	gen := newTestGenerator(t, cfg, 1)
+	gen.Run()
+
+	bytes, keyframes := gen.HistoryStats()
+	full := len(gen.History) * cfg.W * cfg.H * int(unsafe.Sizeof(World2Tile{}))
+	maxKeys := (len(gen.History)+HistoryKeyframeInterval-1)/HistoryKeyframeInterval + 1
+	if keyframes < 1 || keyframes > maxKeys {
+		t.Errorf("%d keyframes for %d snapshots", keyframes, len(gen.History))
+	}
+	if bytes*2 > full {
+		t.Errorf("history %d bytes, full copies %d bytes", bytes, full)
+	}
+	t.Logf("%d snapshots: history %d KB (%d keyframes), full copies %d KB", len(gen.History), bytes/1024, keyframes, full/1024)
+}
+
+// JumpTo で前後どちらへも移動でき、戻した先は RedoStep / NextStep で再適用できること
+func TestJumpToAndRedo(t *testing.T) {
+	gen, frames := runRecording(t, DefaultConfig(), 5)
+	n := len(frames)
+
+	gen.JumpTo(5)
+	if len(gen.History) != 6 || gen.TimelineLen() != n || gen.IsFinished {
+		t.Fatalf("after JumpTo(5): history %d, timeline %d, finished %v", len(gen.History), gen.TimelineLen(), gen.IsFinished)
+	}
+	if !sameTiles(gen.World2.Tiles, frames[5]) {
+		t.Fatalf("JumpTo(5) tiles differ")
+	}
+	gen.RedoStep()
+	if !sameTiles(gen.World2.Tiles, frames[6]) || gen.PhaseName != gen.History[6].PhaseName {
+		t.Fatalf("RedoStep did not restore snapshot 6")
+	}
+	gen.JumpTo(n - 1)
+	if !gen.IsFinished || len(gen.Redo) != 0 || !sameTiles(gen.World2.Tiles, frames[n-1]) {
+		t.Fatalf("JumpTo(last) did not restore the finished map")
+	}
+	gen.JumpTo(2)
+	gen.Run()
+	if !sameTiles(gen.World2.Tiles, frames[n-1]) || gen.TimelineLen() != n {
+		t.Errorf("NextStep after JumpTo did not redo the same steps")
+	}
+}
+
+// マスクの段より前へ戻るとマスクと目標の土の数が消え、マスクの段より後へ進むと元のマスクに戻ること
+func TestJumpToRestoresMasks(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MainType, cfg.SubType, cfg.Ratio = MaskLeftCont, MaskRing, 6
+	gen, _ := runRecording(t, cfg, 4)
+	mask, target := gen.FinalMask, gen.TargetSoilCount
+	if mask == nil || target == 0 {
+		t.Fatalf("finished map has no mask (target %d)", target)
+	}
+
+	before := -1
+	for i, s := range gen.History {
+		if s.StepID <= Phase_MaskGen {
+			before = i
+		}
+	}
+	gen.JumpTo(before)
+	if gen.FinalMask != nil || gen.MaskMain != nil || gen.MaskSub != nil || gen.TargetSoilCount != 0 {
+		t.Fatalf("JumpTo(%d) before the mask phase kept the mask (target %d)", before, gen.TargetSoilCount)
+	}
+	gen.JumpTo(before + 1)
+	if &gen.FinalMask[0][0] != &mask[0][0] || gen.TargetSoilCount != target {
+		t.Errorf("JumpTo(%d) after the mask phase: target %d, want %d", before+1, gen.TargetSoilCount, target)
+	}
+}
+
+// Branch はその時点までの History を残し、以降を新しいシードで生成し直すこと
+func TestBranchRegeneratesFollowingSteps(t *testing.T) {
+	gen, frames := runRecording(t, DefaultConfig(), 6)
+	const at = 3
+	branch := func(seed int64) [][]World2Tile {
+		gen.JumpTo(at)
+		gen.Branch(seed)
+		if len(gen.Redo) != 0 {
+			t.Fatalf("Branch kept %d redo snapshots", len(gen.Redo))
+		}
+		gen.Run()
+		for i := 0; i <= at; i++ {
+			if !sameTiles(gen.SnapshotTiles(i), frames[i]) {
+				t.Fatalf("snapshot %d before the branch point changed", i)
+			}
+		}
+		return copyTiles(gen.World2.Tiles)
+	}
+	a := branch(99)
+	if sameTiles(a, frames[len(frames)-1]) {
+		t.Errorf("branch with a new seed produced the original map")
+	}
+	if b := branch(99); !sameTiles(a, b) {
+		t.Errorf("branch with the same seed is not deterministic")
+	}
+
+	// 完成したマップからは分岐しない (シードも History も変えない)
+	last := gen.History[len(gen.History)-1]
+	if gen.Branch(7) || gen.CurrentSeed == 7 || gen.History[len(gen.History)-1].CurrentSeed != last.CurrentSeed {
+		t.Errorf("Branch on a finished generator changed the seed")
+	}
+}
@@ -0,0 +1,137 @@
+// filename: world2/invariant_test.go
+package world2
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// invariantCase は不変条件を確かめる生成条件1件分
+type invariantCase struct {
+	w, h             int
+	seed             int64
+	main, sub, ratio int
+}
+
+// invariantCases はサイズ・マスク形状・比率を巡回させた生成条件を返す
+// 通常は数百件と最大サイズ、-short では一部だけ
+func invariantCases(short bool) []invariantCase {
+	sizes := [][2]int{{40, 40}, {64, 48}, {100, 70}, {150, 100}, {48, 120}, {220, 140}}
+	n := 240
+	if short {
+		n = 30
+	}
+	var cases []invariantCase
+	for i := 0; i < n; i++ {
+		sz := sizes[i%len(sizes)]
+		cases = append(cases, invariantCase{
+			w: sz[0], h: sz[1], seed: int64(i + 1),
+			main: MinMaskType + i%9, sub: MinMaskType + (i/9)%9, ratio: i % 11,
+		})
+	}
+	if !short {
+		for i, sz := range [][2]int{{MaxSize, MaxSize}, {MaxSize, MinSize}, {MinSize, MaxSize}} {
+			for k := 0; k < 3; k++ {
+				cases = append(cases, invariantCase{
+					w: sz[0], h: sz[1], seed: int64(1000 + i*10 + k),
+					main: MinMaskType + (i*3+k)%9, sub: MinMaskType + (k*4)%9, ratio: 5,
+				})
+			}
+		}
+	}
+	return cases
+}
+
+// checkBorder は外周3マスがすべて固定海かを確かめる
+func checkBorder(m *WorldMap2) error {
+	for x := 0; x < m.Width; x++ {
+		for y := 0; y < m.Height; y++ {
+			if (x < 3 || x >= m.Width-3 || y < 3 || y >= m.Height-3) && m.Tiles[x][y].Type != W2TileFixedOcean {
+				return fmt.Errorf("border tile (%d,%d) is type %d", x, y, m.Tiles[x][y].Type)
+			}
+		}
+	}
+	return nil
+}
+
+// checkLakes は IsLake がちょうど「外周の固定海から陸地を通らずに届かない海と浅瀬」に付いているかを確かめる
+func checkLakes(m *WorldMap2) error {
+	w, h := m.Width, m.Height
+	open := func(t World2Tile) bool { return !isLandType(t.Type) }
+	border := distanceField(m.Tiles, w, h, func(t World2Tile) bool { return t.Type == W2TileFixedOcean }, open)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			t := m.Tiles[x][y]
+			want := (t.Type == W2TileVariableOcean || t.Type == W2TileShallow) && border[x][y] < 0
+			if t.IsLake != want {
+				return fmt.Errorf("tile (%d,%d) type %d: IsLake %v, want %v", x, y, t.Type, t.IsLake, want)
+			}
+		}
+	}
+	return nil
+}
+
+func countType(m *WorldMap2, typ int) int {
+	n := 0
+	for x := 0; x < m.Width; x++ {
+		for y := 0; y < m.Height; y++ {
+			if m.Tiles[x][y].Type == typ {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// runInvariantCase は1件分の生成を段ごとに進め、各段の後と完成後の不変条件を確かめる
+func runInvariantCase(t *testing.T, c invariantCase) {
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = c.w, c.h
+	cfg.MainType, cfg.SubType, cfg.Ratio = c.main, c.sub, c.ratio
+	gen, err := NewGenerator(cfg, c.seed)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+
+	step := -1
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("panic in step %d: %v", step, r)
+		}
+	}()
+	for !gen.IsFinished {
+		step = gen.CurrentStep
+		gen.NextStep()
+		if err := checkBorder(gen.World2); err != nil {
+			t.Fatalf("after step %d (%s): %v", step, gen.PhaseName, err)
+		}
+		if step == Phase_SoilProgressEnd {
+			// 土の配置が終わった時点の土の数は、MinPct..MaxPct から決めた目標ちょうど
+			area := float64(c.w * c.h)
+			lo := int(math.Round(area * float64(cfg.MinPct) / 100))
+			hi := int(math.Round(area * float64(cfg.MaxPct) / 100))
+			soil := countType(gen.World2, W2TileSoil)
+			if soil != gen.TargetSoilCount || soil < lo || soil > hi {
+				t.Fatalf("soil %d after soil progress, target %d, range %d..%d", soil, gen.TargetSoilCount, lo, hi)
+			}
+		}
+	}
+	if err := checkLakes(gen.World2); err != nil {
+		t.Fatalf("lakes: %v", err)
+	}
+	if len(gen.SafetyCapHits) > 0 {
+		t.Fatalf("safety loop caps hit: %v", gen.SafetyCapHits)
+	}
+}
+
+// 多数のシード・サイズ・マスク形状で、どの段も panic せず安全上限にも達せず、不変条件を保つこと
+func TestGeneratorInvariants(t *testing.T) {
+	for _, c := range invariantCases(testing.Short()) {
+		c := c
+		name := fmt.Sprintf("%dx%d/mask%d-%d-r%d/seed%d", c.w, c.h, c.main, c.sub, c.ratio, c.seed)
+		t.Run(name, func(t *testing.T) {
+			runInvariantCase(t, c)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// filename: world2/island_shallow_test.go
+package world2
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// newRingTestGenerator は (30,30) を中心に半径15の円周上へ B航路の経由島を8つ置き、
+// 円の内側と外側に土の塊を1つずつ置いたマップを作る
+func newRingTestGenerator() *World2Generator {
+	gen := newRouteTestGenerator(60, 60)
+	gen.Config.IslandShallow = true
+	m := gen.World2
+	for i := 0; i < 8; i++ {
+		a := float64(i) * math.Pi / 4
+		x, y := int(math.Round(30+15*math.Cos(a))), int(math.Round(30+15*math.Sin(a)))
+		m.Tiles[x][y] = World2Tile{Type: W2TileTransit, Source: SrcBRouteIsland}
+	}
+	for x := 26; x < 30; x++ {
+		for y := 26; y < 30; y++ {
+			m.Tiles[x][y] = World2Tile{Type: W2TileSoil, Source: SrcMain}
+		}
+	}
+	for x := 50; x < 54; x++ {
+		for y := 50; y < 54; y++ {
+			m.Tiles[x][y] = World2Tile{Type: W2TileSoil, Source: SrcMain}
+		}
+	}
+	return gen
+}
+
+// 浅瀬は円の内側にだけ作られ、経由島以外の陸地 (大陸) の周りにも作られること
+func TestIslandShallowOnlyInsideCircle(t *testing.T) {
+	gen := newRingTestGenerator()
+	cx, cy, r, ok := gen.islandShallowCircle(60, 60)
+	if !ok {
+		t.Fatalf("ring of 8 route islands was not detected as a circle")
+	}
+
+	gen.PhaseIslandShallowAdjust(60, 60, rand.New(rand.NewSource(1)))
+
+	if len(gen.NewSoils) == 0 {
+		t.Fatalf("no shallows created (%s)", gen.PhaseName)
+	}
+	for idx := range gen.NewSoils {
+		x, y := idx%60, idx/60
+		if gen.World2.Tiles[x][y].Type != W2TileShallow {
+			t.Errorf("(%d,%d) marked new but is type %d", x, y, gen.World2.Tiles[x][y].Type)
+		}
+		if math.Hypot(float64(x)-cx, float64(y)-cy) >= r {
+			t.Errorf("shallow (%d,%d) is outside the circle (%.1f,%.1f) r=%.1f", x, y, cx, cy, r)
+		}
+	}
+	if gen.World2.Tiles[25][27].Type != W2TileShallow {
+		t.Errorf("continent inside the circle got no shallow coast")
+	}
+	if gen.World2.Tiles[49][51].Type == W2TileShallow {
+		t.Errorf("continent outside the circle got a shallow coast")
+	}
+}
+
+// 円の内側を通る A/B航路は、陸地に接していても浅瀬にならず航路のまま残ること
+func TestIslandShallowKeepsRouteTiles(t *testing.T) {
+	gen := newRingTestGenerator()
+	for x := 20; x < 40; x++ {
+		gen.World2.Tiles[x][25].Source = SrcTransitPath
+	}
+	for y := 26; y < 34; y++ {
+		gen.World2.Tiles[30][y].Source = SrcBRoutePath
+	}
+	gen.PhaseIslandShallowAdjust(60, 60, rand.New(rand.NewSource(1)))
+
+	if gen.World2.Tiles[25][27].Type != W2TileShallow {
+		t.Fatalf("continent inside the circle got no shallow coast (%s)", gen.PhaseName)
+	}
+	for x := 0; x < 60; x++ {
+		for y := 0; y < 60; y++ {
+			tile := gen.World2.Tiles[x][y]
+			if (tile.Source == SrcTransitPath || tile.Source == SrcBRoutePath) && tile.Type != W2TileVariableOcean {
+				t.Errorf("route tile (%d,%d) became type %d", x, y, tile.Type)
+			}
+		}
+	}
+}
+
+// 同じ乱数から同じ結果になること (走査順が固定されていること)
+func TestIslandShallowDeterministic(t *testing.T) {
+	a, b := newRingTestGenerator(), newRingTestGenerator()
+	a.PhaseIslandShallowAdjust(60, 60, rand.New(rand.NewSource(9)))
+	b.PhaseIslandShallowAdjust(60, 60, rand.New(rand.NewSource(9)))
+	if !bytes.Equal(tileBytes(a.World2), tileBytes(b.World2)) {
+		t.Fatalf("same rng produced different shallows")
+	}
+}
+
+// トグルが OFF なら何も変更しないこと、片側に寄った経由島は円とみなさないこと
+func TestIslandShallowSkipped(t *testing.T) {
+	gen := newRingTestGenerator()
+	gen.Config.IslandShallow = false
+	before := tileBytes(gen.World2)
+	gen.PhaseIslandShallowAdjust(60, 60, rand.New(rand.NewSource(1)))
+	if !bytes.Equal(before, tileBytes(gen.World2)) {
+		t.Errorf("phase changed tiles while disabled")
+	}
+
+	gen = newRouteTestGenerator(60, 60)
+	for i := 0; i < 6; i++ {
+		gen.World2.Tiles[10+i*8][10].Type = W2TileTransit
+		gen.World2.Tiles[10+i*8][10].Source = SrcBRouteIsland
+	}
+	if _, _, _, ok := gen.islandShallowCircle(60, 60); ok {
+		t.Errorf("route islands in a straight line were detected as a circle")
+	}
+}
+
+// 生成全体でも、このフェーズで変化したタイルはすべて円の内側の浅瀬であること
+// (経由島が円状に並ぶシードは少ないため、見つかった最初の2つで確認する)
+func TestIslandShallowPipeline(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.IslandShallow = true
+	w, h := cfg.W, cfg.H
+	found := 0
+	for seed := int64(1); seed <= 3000 && found < 2; seed++ {
+		gen := newTestGenerator(t, cfg, seed)
+		for gen.CurrentStep < Phase_IslandShallowAdjust {
+			gen.NextStep()
+		}
+		cx, cy, r, ok := gen.islandShallowCircle(w, h)
+		if !ok {
+			continue
+		}
+		found++
+		before := make([][]World2Tile, w)
+		for x := range before {
+			before[x] = append([]World2Tile(nil), gen.World2.Tiles[x]...)
+		}
+		gen.NextStep()
+
+		changed := 0
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				if gen.World2.Tiles[x][y] == before[x][y] {
+					continue
+				}
+				changed++
+				if before[x][y].Source != SrcNone {
+					t.Errorf("seed %d: (%d,%d) with source %d was turned into shallow", seed, x, y, before[x][y].Source)
+				}
+				if gen.World2.Tiles[x][y].Type != W2TileShallow || math.Hypot(float64(x)-cx, float64(y)-cy) >= r {
+					t.Errorf("seed %d: (%d,%d) changed outside the circle or not to shallow", seed, x, y)
+				}
+			}
+		}
+		if changed == 0 {
+			t.Errorf("seed %d: phase created no shallows (%s)", seed, gen.PhaseName)
+		}
+	}
+	if found == 0 {
+		t.Fatalf("no seed produced a route island circle")
+	}
+}
@@ -0,0 +1,177 @@
+// filename: world2/metrics.go
+package world2
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Metrics はマップの出来を測る指標 (WorldMap2 だけから計算するので、読み込んだマップにも使える)
+type Metrics struct {
+	Land       int     // 陸地 (土・崖・経由島) のタイル数
+	Landmasses int     // 陸地の4方向の連結成分の数
+	Largest    int     // 最大の陸地 (大陸) のタイル数
+	LargestPct float64 // 最大の陸地が陸地全体に占める割合 (%)
+	Islands    int     // 大陸以外の島の数 (経由島だけでできた飛び石は数えない)
+	Coastline  int     // 陸地と海 (湖以外の水域) が接する辺の数
+	Route1     int     // 航路1 (A/B 航路) のタイル数
+	Route2     int     // 航路2 (孤立島を囲む円形航路) のタイル数
+	Roads      int     // 道のタイル数
+	Lakes      []int   // 湖 (4方向の連結成分) ごとのタイル数 (大きい順)
+}
+
+// Metrics はマップの指標を計算する
+func (m *WorldMap2) Metrics() Metrics {
+	w, h := m.Width, m.Height
+	var r Metrics
+	land := components(m.Tiles, w, h, func(t World2Tile) bool { return isLandType(t.Type) })
+	var sizes []int
+	var stones []bool // 経由島だけでできた成分
+	for i, c := range land {
+		if c < 0 {
+			continue
+		}
+		if c == len(sizes) {
+			sizes = append(sizes, 0)
+			stones = append(stones, true)
+		}
+		sizes[c]++
+		if m.Tiles[i%w][i/w].Type != W2TileTransit {
+			stones[c] = false
+		}
+	}
+	mainland := -1
+	for c, n := range sizes {
+		r.Land += n
+		if mainland < 0 || n > sizes[mainland] {
+			mainland = c
+		}
+	}
+	r.Landmasses = len(sizes)
+	if mainland >= 0 {
+		r.Largest = sizes[mainland]
+		r.LargestPct = float64(r.Largest) * 100 / float64(r.Land)
+	}
+	for c := range sizes {
+		if c != mainland && !stones[c] {
+			r.Islands++
+		}
+	}
+
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			t := m.Tiles[x][y]
+			if t.IsRoad {
+				r.Roads++
+			}
+			if t.Type == W2TileVariableOcean {
+				switch t.Source {
+				case SrcTransitPath, SrcBRoutePath:
+					r.Route1++
+				case SrcRoute2Path:
+					r.Route2++
+				}
+			}
+			if !isLandType(t.Type) {
+				continue
+			}
+			// 陸地と海の辺は陸地の側から1回ずつ数える
+			if x+1 < w && isSea(m.Tiles[x+1][y]) {
+				r.Coastline++
+			}
+			if y+1 < h && isSea(m.Tiles[x][y+1]) {
+				r.Coastline++
+			}
+			if x > 0 && isSea(m.Tiles[x-1][y]) {
+				r.Coastline++
+			}
+			if y > 0 && isSea(m.Tiles[x][y-1]) {
+				r.Coastline++
+			}
+		}
+	}
+
+	lakes := components(m.Tiles, w, h, func(t World2Tile) bool { return t.IsLake })
+	for _, c := range lakes {
+		if c < 0 {
+			continue
+		}
+		if c == len(r.Lakes) {
+			r.Lakes = append(r.Lakes, 0)
+		}
+		r.Lakes[c]++
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(r.Lakes)))
+	return r
+}
+
+// components は pass を満たすタイルの4方向の連結成分番号を返す (満たさないタイルは -1)
+func components(tiles [][]World2Tile, w, h int, pass func(World2Tile) bool) []int {
+	comp := make([]int, w*h)
+	for i := range comp {
+		comp[i] = -1
+	}
+	next := 0
+	for start := range comp {
+		if comp[start] >= 0 || !pass(tiles[start%w][start/w]) {
+			continue
+		}
+		comp[start] = next
+		queue := []int{start}
+		for len(queue) > 0 {
+			i := queue[0]
+			queue = queue[1:]
+			for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+				nx, ny := i%w+d.X, i/w+d.Y
+				if nx >= 0 && nx < w && ny >= 0 && ny < h && comp[ny*w+nx] < 0 && pass(tiles[nx][ny]) {
+					comp[ny*w+nx] = next
+					queue = append(queue, ny*w+nx)
+				}
+			}
+		}
+		next++
+	}
+	return comp
+}
+
+// MetricNames は条件 (Constraint) で使える指標の名前
+var MetricNames = []string{"land", "landmasses", "largest", "islands", "coast", "route1", "route2", "roads", "lakes", "lakemax"}
+
+// Value は名前で指標の値を返す ("largest" は割合 %、"lakes" は湖の数、"lakemax" は最大の湖のタイル数)
+func (r Metrics) Value(name string) (float64, bool) {
+	switch name {
+	case "land":
+		return float64(r.Land), true
+	case "landmasses":
+		return float64(r.Landmasses), true
+	case "largest":
+		return r.LargestPct, true
+	case "islands":
+		return float64(r.Islands), true
+	case "coast":
+		return float64(r.Coastline), true
+	case "route1":
+		return float64(r.Route1), true
+	case "route2":
+		return float64(r.Route2), true
+	case "roads":
+		return float64(r.Roads), true
+	case "lakes":
+		return float64(len(r.Lakes)), true
+	case "lakemax":
+		if len(r.Lakes) == 0 {
+			return 0, true
+		}
+		return float64(r.Lakes[0]), true
+	}
+	return 0, false
+}
+
+// Lines は画面と CLI に出す指標の要約を返す
+func (r Metrics) Lines() []string {
+	lakeMax, _ := r.Value("lakemax")
+	return []string{
+		fmt.Sprintf("Land:%d Mass:%d Main:%.0f%% Isl:%d Coast:%d", r.Land, r.Landmasses, r.LargestPct, r.Islands, r.Coastline),
+		fmt.Sprintf("Route1:%d Route2:%d Road:%d Lakes:%d (max %.0f)", r.Route1, r.Route2, r.Roads, len(r.Lakes), lakeMax),
+	}
+}
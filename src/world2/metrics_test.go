@@ -0,0 +1,108 @@
+// filename: world2/metrics_test.go
+package world2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := newPathTestMap([]string{
+		"~~~~~~~~~~",
+		"~####...#~",
+		"~#..#...#~",
+		"~####.#..~",
+		"~.......^~",
+		"~~~~~~~~~~",
+	})
+	// (2,2),(3,2) は陸に囲まれた湖、(6,3) は経由島の飛び石ではなく土の島、(8,4) は崖の島
+	m.Tiles[2][2].IsLake, m.Tiles[3][2].IsLake = true, true
+	m.Tiles[8][1].Type, m.Tiles[8][2].Type = W2TileTransit, W2TileTransit
+	m.Tiles[5][1].Source, m.Tiles[6][1].Source = SrcTransitPath, SrcBRoutePath
+	m.Tiles[5][4].Source = SrcRoute2Path
+	m.Tiles[1][1].IsRoad = true
+
+	r := m.Metrics()
+	if r.Land != 14 || r.Landmasses != 4 || r.Largest != 10 || r.Islands != 2 {
+		t.Errorf("land %d masses %d largest %d islands %d, want 14 4 10 2", r.Land, r.Landmasses, r.Largest, r.Islands)
+	}
+	if want := 10 * 100 / 14.0; r.LargestPct != want {
+		t.Errorf("largest %.2f%%, want %.2f%%", r.LargestPct, want)
+	}
+	// 大陸の外周 14 辺 (湖との辺は数えない)、島 (6,3) の 4 辺、経由島 6 辺、崖 4 辺
+	if r.Coastline != 28 {
+		t.Errorf("coastline %d, want 28", r.Coastline)
+	}
+	if r.Route1 != 2 || r.Route2 != 1 || r.Roads != 1 {
+		t.Errorf("route1 %d route2 %d roads %d", r.Route1, r.Route2, r.Roads)
+	}
+	if len(r.Lakes) != 1 || r.Lakes[0] != 2 {
+		t.Errorf("lakes %v, want [2]", r.Lakes)
+	}
+}
+
+func TestParseConstraints(t *testing.T) {
+	cs, err := ParseConstraints("islands=3, lakes>=1 largest>60%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Constraint{{"islands", "=", 3}, {"lakes", ">=", 1}, {"largest", ">", 60}}
+	if len(cs) != len(want) {
+		t.Fatalf("constraints %v, want %v", cs, want)
+	}
+	for i := range want {
+		if cs[i] != want[i] {
+			t.Errorf("constraint %d: %v, want %v", i, cs[i], want[i])
+		}
+	}
+	m := Metrics{Land: 100, Largest: 70, LargestPct: 70, Islands: 3, Lakes: []int{4}}
+	if !MatchAll(cs, m) {
+		t.Errorf("%v does not match %+v", cs, m)
+	}
+	m.Islands = 4
+	if MatchAll(cs, m) {
+		t.Errorf("%v matches %+v", cs, m)
+	}
+	// 画面の警告 (WarningMsg) にそのまま出すので、大文字で始まる "X must be ..." の形
+	for bad, want := range map[string]string{
+		"islands":  `Constraint "islands": must be metric, operator, value (e.g. lakes>=1)`,
+		"isles=3":  `Constraint "isles=3": metric must be one of `,
+		"lakes>=x": `Constraint "lakes>=x": value must be a number`,
+	} {
+		if _, err := ParseConstraints(bad); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("%q: error %v, want %q", bad, err, want)
+		}
+	}
+}
+
+// 見つかったシードの生成結果が条件を満たし、同じシードの生成と同じになること
+func TestSearchSeed(t *testing.T) {
+	cfg := DefaultConfig()
+	cs, _ := ParseConstraints("islands>=1, lakes>=1")
+	tried := 0
+	gen, err := SearchSeed(cfg, cs, 1, 50, nil, func(seed int64, m Metrics) { tried++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gen.World2.Metrics()
+	if !gen.IsFinished || !MatchAll(cs, r) || gen.StartSeed != int64(tried) {
+		t.Errorf("seed %d after %d tries: finished %v metrics %+v", gen.StartSeed, tried, gen.IsFinished, r)
+	}
+	want, err := Generate(cfg, gen.StartSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tileBytes(gen.World2), tileBytes(want)) {
+		t.Errorf("seed %d: found map differs from a fresh generation", gen.StartSeed)
+	}
+
+	if _, err := SearchSeed(cfg, []Constraint{{"land", "<", 0}}, 1, 3, nil, nil); err != ErrSeedNotFound {
+		t.Errorf("impossible constraint: err %v", err)
+	}
+	s := StartSeedSearch(cfg, []Constraint{{"land", "<", 0}}, 1, 1000)
+	s.Cancel()
+	if _, err := s.Result(); err != ErrSearchCanceled {
+		t.Errorf("canceled search: err %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// filename: world2/nation_test.go
+package world2
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newNationTestTiles は全面を海にした w x h のタイルを作り、rows の '#' を土、'~' を航路の海にする
+func newNationTestTiles(rows []string) ([][]World2Tile, int, int) {
+	w, h := len(rows[0]), len(rows)
+	tiles := make([][]World2Tile, w)
+	for x := range tiles {
+		tiles[x] = make([]World2Tile, h)
+		for y := range tiles[x] {
+			switch rows[y][x] {
+			case '#':
+				tiles[x][y] = World2Tile{Type: W2TileSoil}
+			case '~':
+				tiles[x][y] = World2Tile{Type: W2TileVariableOcean, Source: SrcTransitPath}
+			default:
+				tiles[x][y] = World2Tile{Type: W2TileVariableOcean}
+			}
+		}
+	}
+	return tiles, w, h
+}
+
+// 首都は土の上にあり、その国に属し、陸地と航路でつながる陸地はすべてどこかの国に属すること
+func TestNationsCoverConnectedLand(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		cfg := DefaultConfig()
+		gen := newTestGenerator(t, cfg, seed)
+		gen.Run()
+		m := gen.World2
+		if len(m.Capitals) == 0 || len(m.Capitals) > cfg.NationCount {
+			t.Fatalf("seed %d: %d capitals for NationCount %d", seed, len(m.Capitals), cfg.NationCount)
+		}
+		for i, c := range m.Capitals {
+			tile := m.Tiles[c.X][c.Y]
+			if tile.Type != W2TileSoil || tile.Nation != uint8(i+1) {
+				t.Errorf("seed %d: capital %d at (%d,%d) is type %d nation %d", seed, i, c.X, c.Y, tile.Type, tile.Nation)
+			}
+		}
+
+		// 首都から陸地と航路をたどって届くタイルを数え直す
+		reach := make([][]bool, m.Width)
+		for x := range reach {
+			reach[x] = make([]bool, m.Height)
+		}
+		queue := append([]Point(nil), m.Capitals...)
+		for _, c := range queue {
+			reach[c.X][c.Y] = true
+		}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+				nx, ny := p.X+d.X, p.Y+d.Y
+				if nx >= 0 && nx < m.Width && ny >= 0 && ny < m.Height && !reach[nx][ny] && isNationPassable(m.Tiles[nx][ny]) {
+					reach[nx][ny] = true
+					queue = append(queue, Point{nx, ny})
+				}
+			}
+		}
+		for x := 0; x < m.Width; x++ {
+			for y := 0; y < m.Height; y++ {
+				tile := m.Tiles[x][y]
+				switch {
+				case !isLandType(tile.Type) && tile.Nation != 0:
+					t.Fatalf("seed %d: water (%d,%d) has nation %d", seed, x, y, tile.Nation)
+				case isLandType(tile.Type) && reach[x][y] != (tile.Nation != 0):
+					t.Fatalf("seed %d: land (%d,%d) reachable %v but nation %d", seed, x, y, reach[x][y], tile.Nation)
+				}
+			}
+		}
+	}
+}
+
+// 国境は直線距離ではなく陸地をたどる距離で決まり、航路でつながる島は国に入り、孤島は無所属になること
+func TestAssignNationsByGraphDistance(t *testing.T) {
+	tiles, w, h := newNationTestTiles([]string{
+		"#######.....",
+		"......#.....",
+		"#######.....",
+		"#...........",
+		"#~~~~~~~~##.",
+		"..........#.",
+		"...........#",
+		"....##......",
+	})
+	// 首都 A は (0,0)、首都 B は (0,3) (A のすぐ下だが、陸地をたどると遠い)
+	assignNations(tiles, w, h, []Point{{0, 0}, {0, 3}})
+
+	for _, tc := range []struct {
+		x, y int
+		want uint8
+	}{
+		{6, 1, 1},  // A から 7 マス、B から 8 マス
+		{0, 2, 2},  // A とは直線で 2 マスだが、陸地をたどると B が近い
+		{9, 4, 2},  // B から航路でつながる島
+		{10, 5, 2}, // 同じ島の続き
+		{11, 6, 0}, // 斜めにしか接していない孤島
+		{4, 7, 0},  // 孤島
+	} {
+		if got := tiles[tc.x][tc.y].Nation; got != tc.want {
+			t.Errorf("(%d,%d): nation %d, want %d", tc.x, tc.y, got, tc.want)
+		}
+	}
+	if tiles[3][4].Nation != 0 {
+		t.Errorf("transit path got nation %d", tiles[3][4].Nation)
+	}
+}
+
+func TestNationsDisabled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.NationCount = 0
+	gen := newTestGenerator(t, cfg, 1)
+	gen.Run()
+	if got := executedName(gen, Phase_Nations); got != "Nations (Skipped)" {
+		t.Errorf("phase %q, want Nations (Skipped)", got)
+	}
+	if len(gen.World2.Capitals) != 0 {
+		t.Errorf("%d capitals with NationCount 0", len(gen.World2.Capitals))
+	}
+	for x := 0; x < gen.World2.Width; x++ {
+		for y := 0; y < gen.World2.Height; y++ {
+			if gen.World2.Tiles[x][y].Nation != 0 {
+				t.Fatalf("(%d,%d) has nation %d", x, y, gen.World2.Tiles[x][y].Nation)
+			}
+		}
+	}
+}
+
+// 首都は保存・読み込みと、Undo で戻った段でも元どおりになること
+func TestCapitalsSurviveSaveAndUndo(t *testing.T) {
+	gen := newTestGenerator(t, DefaultConfig(), 7)
+	gen.Run()
+	want := append([]Point(nil), gen.World2.Capitals...)
+
+	var buf bytes.Buffer
+	if err := gen.SaveData().WriteBinary(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	d, err := ReadSave(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	loaded, err := NewGeneratorFromSave(d)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromSave: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.World2.Capitals, want) {
+		t.Errorf("loaded capitals %v, want %v", loaded.World2.Capitals, want)
+	}
+
+	nations := -1
+	for i, s := range gen.History {
+		if strings.HasPrefix(s.PhaseName, fmt.Sprintf("%d. ", Phase_Nations)) {
+			nations = i
+		}
+	}
+	if nations < 1 {
+		t.Fatalf("nations phase not in history")
+	}
+	gen.JumpTo(nations - 1)
+	if len(gen.World2.Capitals) != 0 {
+		t.Errorf("capitals %v remain before the nations phase", gen.World2.Capitals)
+	}
+	gen.JumpTo(nations)
+	if !reflect.DeepEqual(gen.World2.Capitals, want) {
+		t.Errorf("redo capitals %v, want %v", gen.World2.Capitals, want)
+	}
+}
@@ -0,0 +1,179 @@
+// filename: world2/palette.go
+package world2
+
+import (
+	"image/color"
+)
+
+// TileColor は DrawWorld2 と PNG 出力で共通のタイル色を返す (isNew: 直前のステップで変化したタイル)
+func TileColor(tile World2Tile, isNew bool) color.RGBA {
+	var c color.RGBA
+	switch tile.Type {
+	case W2TileSoil:
+		if tile.IsRiver {
+			c = color.RGBA{30, 144, 255, 255} // 川（明るい青）
+			if isNew {
+				c = color.RGBA{120, 190, 255, 255}
+			}
+		} else if isNew {
+			c = color.RGBA{210, 180, 140, 255}
+		} else {
+			switch tile.Source {
+			case SrcMain:
+				c = color.RGBA{180, 100, 80, 255}
+			case SrcSub:
+				c = color.RGBA{100, 160, 80, 255}
+			case SrcMix:
+				c = color.RGBA{160, 100, 160, 255}
+			case SrcBridge:
+				c = color.RGBA{150, 150, 160, 255}
+			case SrcIsland:
+				c = color.RGBA{230, 190, 100, 255}
+			case SrcBRouteIsland:
+				c = color.RGBA{100, 180, 100, 255} // B航路の経由島（緑）
+			default:
+				c = color.RGBA{139, 69, 19, 255}
+			}
+		}
+	case W2TileVariableOcean:
+		if tile.IsLake {
+			c = color.RGBA{60, 100, 200, 255}
+		} else {
+			c = color.RGBA{30, 60, 180, 255}
+		}
+		// 航路の色付け
+		if tile.Source == SrcTransitPath {
+			c = color.RGBA{40, 80, 160, 255} // A航路（青系）
+		}
+		if tile.Source == SrcBRoutePath {
+			c = color.RGBA{50, 120, 80, 255} // B航路（暗緑）
+		}
+		if tile.Source == SrcRoute2Path {
+			c = color.RGBA{130, 80, 170, 255} // 航路2（紫）
+		}
+	case W2TileFixedOcean:
+		c = color.RGBA{10, 20, 80, 255}
+	case W2TileTransit:
+		if tile.Source == SrcBRouteIsland {
+			c = color.RGBA{100, 180, 100, 255} // B航路の経由島（緑）
+		} else {
+			c = color.RGBA{200, 180, 80, 255} // A航路の経由島（黄色）
+		}
+	case W2TileCliff:
+		c = color.RGBA{80, 40, 10, 255}
+		if isNew {
+			c = color.RGBA{120, 60, 30, 255}
+		}
+	case W2TileShallow:
+		c = color.RGBA{60, 160, 200, 255}
+		if isNew {
+			c = color.RGBA{100, 200, 255, 255}
+		}
+	}
+	return c
+}
+
+// Hillshade は北西からの光で (x, y) の標高の陰影係数を返す (1 が平地、明るい斜面ほど大きい)
+func Hillshade(tiles [][]World2Tile, x, y int) float64 {
+	w, h := len(tiles), len(tiles[0])
+	elev := func(x, y int) float64 {
+		if x < 0 { x = 0 }
+		if x >= w { x = w - 1 }
+		if y < 0 { y = 0 }
+		if y >= h { y = h - 1 }
+		return float64(tiles[x][y].Elevation)
+	}
+	// 北西側が高ければ光が当たらない斜面 (暗い)、南東側が高ければ光に向いた斜面 (明るい)
+	slope := (elev(x+1, y+1) - elev(x-1, y-1)) / 2
+	f := 1 + slope*0.04
+	if f < 0.5 { f = 0.5 }
+	if f > 1.4 { f = 1.4 }
+	return f
+}
+
+// ShadeColor は色の明るさに陰影係数 f をかける
+func ShadeColor(c color.RGBA, f float64) color.RGBA {
+	scale := func(v uint8) uint8 {
+		s := float64(v) * f
+		if s > 255 { s = 255 }
+		return uint8(s)
+	}
+	return color.RGBA{scale(c.R), scale(c.G), scale(c.B), c.A}
+}
+
+// BiomeColor はバイオームの色を返す。色の元はここで、World1 の TexW_Ocean..TexW_Mountain も起動時にこの色で塗る (resources.go)
+// Tundra と Swamp には対応する World1 のテクスチャはない
+func BiomeColor(biome uint8) color.RGBA {
+	switch biome {
+	case BiomePlains:
+		return color.RGBA{100, 160, 80, 255}
+	case BiomeForest:
+		return color.RGBA{40, 100, 50, 255}
+	case BiomeDesert:
+		return color.RGBA{200, 180, 100, 255}
+	case BiomeMountain:
+		return color.RGBA{120, 110, 100, 255}
+	case BiomeTundra:
+		return color.RGBA{210, 220, 225, 255}
+	case BiomeSwamp:
+		return color.RGBA{70, 90, 60, 255}
+	}
+	return color.RGBA{20, 60, 120, 255}
+}
+
+// BiomeTileColor はバイオーム表示でのタイル色を返す (陸地はバイオーム色、水域と川は TileColor のまま)
+func BiomeTileColor(tile World2Tile, isNew bool) color.RGBA {
+	if isLandType(tile.Type) && !tile.IsRiver {
+		return BiomeColor(tile.Biome)
+	}
+	return TileColor(tile, isNew)
+}
+
+// nationPalette は国ごとの色 (国の数が多い場合は繰り返す)
+var nationPalette = []color.RGBA{
+	{220, 80, 80, 255}, {80, 140, 220, 255}, {230, 190, 60, 255}, {90, 180, 90, 255},
+	{170, 90, 200, 255}, {240, 140, 60, 255}, {70, 190, 190, 255}, {210, 110, 170, 255},
+	{150, 150, 70, 255}, {120, 100, 220, 255}, {180, 120, 80, 255}, {110, 200, 140, 255},
+}
+
+// NationColor は国 (1..) の色を返す。0 (無所属) は灰色
+func NationColor(nation uint8) color.RGBA {
+	if nation == 0 {
+		return color.RGBA{150, 150, 150, 255}
+	}
+	return nationPalette[int(nation-1)%len(nationPalette)]
+}
+
+// NationTileColor は国表示でのタイル色を返す (陸地は国の色、水域は TileColor のまま)
+func NationTileColor(tile World2Tile, isNew bool) color.RGBA {
+	if isLandType(tile.Type) {
+		return NationColor(tile.Nation)
+	}
+	return TileColor(tile, isNew)
+}
+
+// IsNationBorder は (x, y) の陸地が、隣 (右・下) の陸地と別の国かを返す (国境線の描画用)
+func IsNationBorder(tiles [][]World2Tile, x, y, dx, dy int) bool {
+	nx, ny := x+dx, y+dy
+	if nx < 0 || ny < 0 || nx >= len(tiles) || ny >= len(tiles[0]) {
+		return false
+	}
+	a, b := tiles[x][y], tiles[nx][ny]
+	return isLandType(a.Type) && isLandType(b.Type) && a.Nation != b.Nation
+}
+
+// RoadColor は道の色 (World1 の IsRoad と同じ茶色)
+var RoadColor = color.RGBA{100, 50, 0, 200}
+
+// SettlementColor は集落の種類ごとの色を返す (アイコンを使わない PNG 出力などで使う)
+func SettlementColor(kind uint8) color.RGBA {
+	switch kind {
+	case SettleCity:
+		return color.RGBA{200, 50, 50, 255}
+	case SettleTown:
+		return color.RGBA{230, 150, 60, 255}
+	case SettlePort:
+		return color.RGBA{240, 240, 255, 255}
+	}
+	return color.RGBA{}
+}
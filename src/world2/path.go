@@ -0,0 +1,156 @@
+// filename: world2/path.go
+package world2
+
+import (
+	"container/heap"
+	"errors"
+)
+
+// MoveCosts は World2 の移動コスト表 (1マスに入るのにかかるターン数。0 以下は通れない)
+// 1ターンは Party.TotalTurns と同じ 6 分
+type MoveCosts struct {
+	Soil    int // 土
+	Road    int // 道 (土・崖・浅瀬のどれでも、道があればこの値)
+	Cliff   int // 崖
+	Shallow int // 浅瀬 (徒歩で渡る)
+	Transit int // 経由島
+	Ship    int // 海と湖 (船で進む)
+	Embark  int // 陸地と水域の間の乗り降り (港では 0)
+}
+
+// DefaultMoveCosts は settings で指定がない時の移動コスト表を返す
+func DefaultMoveCosts() MoveCosts {
+	return MoveCosts{Soil: 2, Road: 1, Cliff: 8, Shallow: 4, Transit: 2, Ship: 3, Embark: 10}
+}
+
+// ApplySettings は settings.txt の Move* の値を移動コスト表に適用する
+func (c *MoveCosts) ApplySettings(settings map[string]string) {
+	ApplyIntSetting(settings, "MoveSoil", &c.Soil)
+	ApplyIntSetting(settings, "MoveRoad", &c.Road)
+	ApplyIntSetting(settings, "MoveCliff", &c.Cliff)
+	ApplyIntSetting(settings, "MoveShallow", &c.Shallow)
+	ApplyIntSetting(settings, "MoveTransit", &c.Transit)
+	ApplyIntSetting(settings, "MoveShip", &c.Ship)
+	ApplyIntSetting(settings, "MoveEmbark", &c.Embark)
+}
+
+// Cost は tile に入る時のコストを返す (0 以下は通れない。外周の固定海は常に通れない)
+func (c MoveCosts) Cost(t World2Tile) int {
+	if t.IsRoad && c.Road > 0 {
+		return c.Road
+	}
+	switch t.Type {
+	case W2TileSoil:
+		return c.Soil
+	case W2TileCliff:
+		return c.Cliff
+	case W2TileShallow:
+		return c.Shallow
+	case W2TileTransit:
+		return c.Transit
+	case W2TileVariableOcean:
+		return c.Ship
+	}
+	return 0
+}
+
+// isShipTile は船で進むタイル (海と湖) かを返す
+func isShipTile(t World2Tile) bool {
+	return t.Type == W2TileVariableOcean
+}
+
+// stepCost は from から隣の to へ進むコストを返す (0 以下は通れない)
+func (c MoveCosts) stepCost(from, to World2Tile) int {
+	cost := c.Cost(to)
+	if cost <= 0 {
+		return cost
+	}
+	if isShipTile(from) != isShipTile(to) && from.Settlement != SettlePort && to.Settlement != SettlePort {
+		cost += c.Embark
+	}
+	return cost
+}
+
+// minCost は A* の推定に使う1マスあたりの最小コストを返す
+func (c MoveCosts) minCost() int {
+	m := 0
+	for _, v := range []int{c.Soil, c.Road, c.Cliff, c.Shallow, c.Transit, c.Ship} {
+		if v > 0 && (m == 0 || v < m) {
+			m = v
+		}
+	}
+	return m
+}
+
+// ErrNoPath は FindPath で経路が見つからない時のエラー
+var ErrNoPath = errors.New("no path")
+
+// FindPath は from から to までの移動コストが最小の経路を A* で探し、経路 (from と to を含む) と合計ターン数を返す
+func (m *WorldMap2) FindPath(from, to Point, costs MoveCosts) ([]Point, int, error) {
+	w, h := m.Width, m.Height
+	inside := func(p Point) bool { return p.X >= 0 && p.X < w && p.Y >= 0 && p.Y < h }
+	if !inside(from) || !inside(to) {
+		return nil, 0, errors.New("point outside the map")
+	}
+	unit := costs.minCost()
+	if unit <= 0 || costs.Cost(m.Tiles[from.X][from.Y]) <= 0 || costs.Cost(m.Tiles[to.X][to.Y]) <= 0 {
+		return nil, 0, ErrNoPath
+	}
+	estimate := func(x, y int) int {
+		dx, dy := x-to.X, y-to.Y
+		if dx < 0 {
+			dx = -dx
+		}
+		if dy < 0 {
+			dy = -dy
+		}
+		return (dx + dy) * unit
+	}
+
+	const unseen = -1
+	dist := make([]int, w*h)
+	prev := make([]int, w*h)
+	for i := range dist {
+		dist[i] = unseen
+		prev[i] = -1
+	}
+	start, goal := from.Y*w+from.X, to.Y*w+to.X
+	dist[start] = 0
+	q := &floodQueue{{level: estimate(from.X, from.Y), idx: start}}
+	for q.Len() > 0 {
+		it := heap.Pop(q).(floodItem)
+		x, y := it.idx%w, it.idx/w
+		if it.level != dist[it.idx]+estimate(x, y) {
+			continue // より短い経路で既に取り出し済み
+		}
+		if it.idx == goal {
+			break
+		}
+		for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+			nx, ny := x+d.X, y+d.Y
+			if nx < 0 || nx >= w || ny < 0 || ny >= h {
+				continue
+			}
+			c := costs.stepCost(m.Tiles[x][y], m.Tiles[nx][ny])
+			n := ny*w + nx
+			if c <= 0 || (dist[n] != unseen && dist[it.idx]+c >= dist[n]) {
+				continue
+			}
+			dist[n] = dist[it.idx] + c
+			prev[n] = it.idx
+			heap.Push(q, floodItem{level: dist[n] + estimate(nx, ny), idx: n})
+		}
+	}
+	if dist[goal] == unseen {
+		return nil, 0, ErrNoPath
+	}
+
+	var path []Point
+	for i := goal; i >= 0; i = prev[i] {
+		path = append(path, Point{i % w, i / w})
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, dist[goal], nil
+}
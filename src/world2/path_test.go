@@ -0,0 +1,155 @@
+// filename: world2/path_test.go
+package world2
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// newPathTestMap は rows の文字からマップを作る ('#' 土, '=' 道, '^' 崖, ',' 浅瀬, 'P' 港, '.' 海, '~' 固定海)
+func newPathTestMap(rows []string) *WorldMap2 {
+	w, h := len(rows[0]), len(rows)
+	m := &WorldMap2{Width: w, Height: h, Tiles: make([][]World2Tile, w)}
+	for x := range m.Tiles {
+		m.Tiles[x] = make([]World2Tile, h)
+		for y := range m.Tiles[x] {
+			var t World2Tile
+			switch rows[y][x] {
+			case '#':
+				t.Type = W2TileSoil
+			case '=':
+				t = World2Tile{Type: W2TileSoil, IsRoad: true}
+			case '^':
+				t.Type = W2TileCliff
+			case ',':
+				t.Type = W2TileShallow
+			case 'P':
+				t = World2Tile{Type: W2TileSoil, Settlement: SettlePort}
+			case '~':
+				t.Type = W2TileFixedOcean
+			default:
+				t.Type = W2TileVariableOcean
+			}
+			m.Tiles[x][y] = t
+		}
+	}
+	return m
+}
+
+func TestFindPathUsesCostTable(t *testing.T) {
+	costs := DefaultMoveCosts()
+	m := newPathTestMap([]string{
+		"#^^^#",
+		"#===#",
+		"#####",
+	})
+	path, turns, err := m.FindPath(Point{0, 0}, Point{4, 0}, costs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 崖を越える (8*3+2=26) より、道を通る (2+1+1+1+2+2=9) 方が安い
+	if want := costs.Soil*3 + costs.Road*3; turns != want {
+		t.Errorf("turns %d, want %d (path %v)", turns, want, path)
+	}
+	if path[0] != (Point{0, 0}) || path[len(path)-1] != (Point{4, 0}) {
+		t.Errorf("path %v does not run from start to goal", path)
+	}
+	for i := 1; i < len(path); i++ {
+		dx, dy := path[i].X-path[i-1].X, path[i].Y-path[i-1].Y
+		if dx*dx+dy*dy != 1 {
+			t.Fatalf("path %v is not 4-connected", path)
+		}
+	}
+
+	costs.Road = 0 // 道を無効にすると土の値になる
+	if _, turns, _ := m.FindPath(Point{0, 0}, Point{4, 0}, costs); turns != costs.Soil*6 {
+		t.Errorf("without roads: turns %d, want %d", turns, costs.Soil*6)
+	}
+}
+
+// 海は船で渡り、乗り降りのコストは港では掛からないこと。固定海には入れないこと
+func TestFindPathBySea(t *testing.T) {
+	costs := DefaultMoveCosts()
+	m := newPathTestMap([]string{
+		"~~~~~~",
+		"#...P#",
+		"~~~~~~",
+	})
+	_, turns, err := m.FindPath(Point{0, 1}, Point{5, 1}, costs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 乗船 (Ship+Embark) + 海 2 マス + 港で下船 (Soil) + 土 1 マス
+	if want := costs.Ship*3 + costs.Embark + costs.Soil*2; turns != want {
+		t.Errorf("turns %d, want %d", turns, want)
+	}
+	if _, _, err := m.FindPath(Point{0, 1}, Point{0, 0}, costs); err != ErrNoPath {
+		t.Errorf("path into fixed ocean: err %v", err)
+	}
+	costs.Ship = 0
+	if _, _, err := m.FindPath(Point{0, 1}, Point{5, 1}, costs); err != ErrNoPath {
+		t.Errorf("path without ships: err %v", err)
+	}
+}
+
+// A* の結果が、推定なしの全探索 (ダイクストラ) と同じ最小コストになること
+func TestFindPathMatchesDijkstra(t *testing.T) {
+	m, err := Generate(DefaultConfig(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	costs := DefaultMoveCosts()
+	w, h := m.Width, m.Height
+	rng := rand.New(rand.NewSource(1))
+	for k := 0; k < 10; k++ {
+		from := Point{rng.Intn(w), rng.Intn(h)}
+		to := Point{rng.Intn(w), rng.Intn(h)}
+
+		dist := make([]int, w*h)
+		for i := range dist {
+			dist[i] = -1
+		}
+		if costs.Cost(m.Tiles[from.X][from.Y]) > 0 {
+			dist[from.Y*w+from.X] = 0
+		}
+		for changed := true; changed; {
+			changed = false
+			for i, d := range dist {
+				if d < 0 {
+					continue
+				}
+				for _, dd := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+					nx, ny := i%w+dd.X, i/w+dd.Y
+					if nx < 0 || nx >= w || ny < 0 || ny >= h {
+						continue
+					}
+					c := costs.stepCost(m.Tiles[i%w][i/w], m.Tiles[nx][ny])
+					if n := ny*w + nx; c > 0 && (dist[n] < 0 || d+c < dist[n]) {
+						dist[n] = d + c
+						changed = true
+					}
+				}
+			}
+		}
+
+		_, turns, err := m.FindPath(from, to, costs)
+		want := dist[to.Y*w+to.X]
+		switch {
+		case want < 0 && err != ErrNoPath:
+			t.Errorf("%v -> %v: unreachable but err %v", from, to, err)
+		case want >= 0 && (err != nil || turns != want):
+			t.Errorf("%v -> %v: turns %d err %v, want %d", from, to, turns, err, want)
+		}
+	}
+}
+
+func TestMoveCostsFromSettings(t *testing.T) {
+	c := DefaultMoveCosts()
+	c.ApplySettings(map[string]string{"MoveCliff": "30", "MoveShip": "0"})
+	if c.Cliff != 30 || c.Ship != 0 || c.Soil != DefaultMoveCosts().Soil {
+		t.Errorf("costs %+v", c)
+	}
+	if c.Cost(World2Tile{Type: W2TileVariableOcean}) > 0 {
+		t.Errorf("sea is passable with MoveShip 0")
+	}
+}
@@ -0,0 +1,159 @@
+// filename: world2/phase_bridge.go
+package world2
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// bridgeMinSize 未満の小さな土の塊は橋で結ばない
+const bridgeMinSize = 4
+
+func (gen *World2Generator) PhaseBridge(w, h int, rng *rand.Rand) {
+	// 連結諸島 (Type 8) 以外は橋を架けない
+	if !gen.Config.UsesMask(MaskLinkedIsles) {
+		gen.PhaseName = "Bridge (Skipped)"
+		return
+	}
+
+	// 1. 土の連結成分 (4近傍) にラベルを付ける
+	comp := make([]int, w*h)
+	for i := range comp { comp[i] = -1 }
+	dxs := []int{0, 1, 0, -1}
+	dys := []int{-1, 0, 1, 0}
+	type P struct{ x, y int }
+	var sizes []int
+	for x := 3; x < w-3; x++ {
+		for y := 3; y < h-3; y++ {
+			if gen.World2.Tiles[x][y].Type != W2TileSoil || comp[y*w+x] >= 0 {
+				continue
+			}
+			id := len(sizes)
+			size := 0
+			comp[y*w+x] = id
+			queue := []P{{x, y}}
+			for len(queue) > 0 {
+				p := queue[0]
+				queue = queue[1:]
+				size++
+				for d := 0; d < 4; d++ {
+					nx, ny := p.x+dxs[d], p.y+dys[d]
+					if nx < 3 || nx >= w-3 || ny < 3 || ny >= h-3 {
+						continue
+					}
+					if gen.World2.Tiles[nx][ny].Type == W2TileSoil && comp[ny*w+nx] < 0 {
+						comp[ny*w+nx] = id
+						queue = append(queue, P{nx, ny})
+					}
+				}
+			}
+			sizes = append(sizes, size)
+		}
+	}
+
+	// 2. 対象の塊から海へ同時に BFS し、領域が接した所を橋の候補にする
+	owner := make([]int, w*h)
+	origin := make([]P, w*h)
+	dist := make([]int, w*h)
+	queue := []P{}
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			i := y*w + x
+			owner[i] = -1
+			if c := comp[i]; c >= 0 && sizes[c] >= bridgeMinSize {
+				owner[i] = c
+				origin[i] = P{x, y}
+				queue = append(queue, P{x, y})
+			}
+		}
+	}
+
+	type Edge struct {
+		a, b, length int
+		from, to     P
+	}
+	best := map[[2]int]Edge{}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		i := p.y*w + p.x
+		for d := 0; d < 4; d++ {
+			nx, ny := p.x+dxs[d], p.y+dys[d]
+			if nx < 3 || nx >= w-3 || ny < 3 || ny >= h-3 {
+				continue
+			}
+			j := ny*w + nx
+			if owner[j] < 0 {
+				if gen.World2.Tiles[nx][ny].Type != W2TileVariableOcean {
+					continue
+				}
+				owner[j] = owner[i]
+				origin[j] = origin[i]
+				dist[j] = dist[i] + 1
+				queue = append(queue, P{nx, ny})
+				continue
+			}
+			if owner[j] == owner[i] {
+				continue
+			}
+			e := Edge{a: owner[i], b: owner[j], length: dist[i] + dist[j] + 1, from: origin[i], to: origin[j]}
+			if e.a > e.b {
+				e.a, e.b, e.from, e.to = e.b, e.a, e.to, e.from
+			}
+			key := [2]int{e.a, e.b}
+			if old, ok := best[key]; !ok || e.length < old.length {
+				best[key] = e
+			}
+		}
+	}
+
+	// 3. 最小全域木 (Kruskal) の辺だけを橋にする
+	edges := make([]Edge, 0, len(best))
+	for _, e := range best {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].length != edges[j].length {
+			return edges[i].length < edges[j].length
+		}
+		if edges[i].a != edges[j].a {
+			return edges[i].a < edges[j].a
+		}
+		return edges[i].b < edges[j].b
+	})
+
+	parent := make([]int, len(sizes))
+	for i := range parent { parent[i] = i }
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	bridges, bridgeTiles := 0, 0
+	for _, e := range edges {
+		ra, rb := find(e.a), find(e.b)
+		if ra == rb {
+			continue
+		}
+		parent[ra] = rb
+		bridges++
+		Bresenham4(e.from.x, e.from.y, e.to.x, e.to.y, func(x, y int) {
+			// 端点はどちらも内側にあるので線が外周3マスの固定海に入ることはないが、念のため確認
+			if x < 3 || x >= w-3 || y < 3 || y >= h-3 {
+				return
+			}
+			if gen.World2.Tiles[x][y].Type == W2TileVariableOcean {
+				gen.World2.Tiles[x][y].Type = W2TileSoil
+				gen.World2.Tiles[x][y].Source = SrcBridge
+				gen.NewSoils[y*w+x] = true
+				bridgeTiles++
+			}
+		})
+	}
+
+	gen.PhaseName = fmt.Sprintf("Bridge (%d links, %d tiles)", bridges, bridgeTiles)
+}
@@ -1,18 +1,18 @@
 // filename: world2/phase_centering.go
-package main
+package world2
 
 import (
 	"math/rand"
 )
 
-func (g *Game) PhaseCentering(w, h int, rng *rand.Rand, gen *World2Generator) {
+func (gen *World2Generator) PhaseCentering(w, h int, rng *rand.Rand) {
 	if gen.Config.Centering {
-		gen.PhaseName = "5. Safe Centering"
+		gen.PhaseName = "Safe Centering"
 		minX, minY, maxX, maxY := w, h, 0, 0
 		hasLand := false
 		for x := 0; x < w; x++ {
 			for y := 0; y < h; y++ {
-				t := g.World2.Tiles[x][y].Type
+				t := gen.World2.Tiles[x][y].Type
 				if t == W2TileSoil || t == W2TileTransit {
 					if x < minX { minX = x }
 					if x > maxX { maxX = x }
@@ -43,10 +43,10 @@ func (g *Game) PhaseCentering(w, h int, rng *rand.Rand, gen *World2Generator) {
 			}
 			for x := 0; x < w; x++ {
 				for y := 0; y < h; y++ {
-					if g.World2.Tiles[x][y].Type == W2TileSoil || g.World2.Tiles[x][y].Type == W2TileTransit {
+					if gen.World2.Tiles[x][y].Type == W2TileSoil || gen.World2.Tiles[x][y].Type == W2TileTransit {
 						nx, ny := x+shiftX, y+shiftY
 						if nx >= 0 && nx < w && ny >= 0 && ny < h {
-							newTiles[nx][ny] = g.World2.Tiles[x][y]
+							newTiles[nx][ny] = gen.World2.Tiles[x][y]
 						}
 					}
 				}
@@ -59,9 +59,9 @@ func (g *Game) PhaseCentering(w, h int, rng *rand.Rand, gen *World2Generator) {
 					}
 				}
 			}
-			g.World2.Tiles = newTiles
+			gen.World2.Tiles = newTiles
 		}
 	} else {
-		gen.PhaseName = "5. Safe Centering (Skipped)"
+		gen.PhaseName = "Safe Centering (Skipped)"
 	}
 }
\ No newline at end of file
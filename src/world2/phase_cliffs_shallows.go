@@ -0,0 +1,199 @@
+// filename: world2/phase_cliffs_shallows.go
+package world2
+
+import (
+	"math"
+	"math/rand"
+)
+
+func (gen *World2Generator) PhaseCliffsShallows(w, h int, rng *rand.Rand) {
+    _ = math.Abs(0) // math の利用を明示
+
+	gen.PhaseName = "Cliffs & Shallows"
+	type P struct { x, y int }
+	isCoastal := func(x, y int) bool {
+		if gen.World2.Tiles[x][y].Type != W2TileSoil && gen.World2.Tiles[x][y].Type != W2TileTransit { return false } 
+		dxs := []int{0, 1, 0, -1}
+		dys := []int{-1, 0, 1, 0}
+		for i := 0; i < 4; i++ {
+			nx, ny := x+dxs[i], y+dys[i]
+			if nx >= 0 && nx < w && ny >= 0 && ny < h && (gen.World2.Tiles[nx][ny].Type == W2TileVariableOcean || gen.World2.Tiles[nx][ny].Type == W2TileShallow) {
+				return true
+			}
+		}
+		return false
+	}
+	maxPathLen := gen.Config.CliffPathLen
+	if maxPathLen <= 0 { maxPathLen = 5 }
+
+	// 幅優先探索は各マスに親だけを記録し、見つかった時に経路を組み立てる
+	// maxPathLen より長い経路は使われないので、それより深くは探さない (結果は全域を探した時と同じ)
+	visited := make([]int32, w*h) // 探索ごとの番号 (search) と同じなら訪問済み
+	parent := make([]int32, w*h)
+	depth := make([]int32, w*h)
+	queue := make([]int32, 0, 64)
+	search := int32(0)
+	findPath := func(sx, sy, ex, ey int) []P {
+		search++
+		start, goal := int32(sy*w+sx), int32(ey*w+ex)
+		visited[start], parent[start], depth[start] = search, -1, 1
+		queue = append(queue[:0], start)
+		dxs := []int{0, 1, 0, -1}
+		dys := []int{-1, 0, 1, 0}
+		for head := 0; head < len(queue); head++ {
+			curr := queue[head]
+			if curr == goal {
+				path := make([]P, depth[curr])
+				for i, n := len(path)-1, curr; i >= 0; i, n = i-1, parent[n] {
+					path[i] = P{int(n) % w, int(n) / w}
+				}
+				return path
+			}
+			if int(depth[curr]) >= maxPathLen { continue }
+			cx, cy := int(curr)%w, int(curr)/w
+			for i := 0; i < 4; i++ {
+				nx, ny := cx+dxs[i], cy+dys[i]
+				if nx >= 0 && nx < w && ny >= 0 && ny < h {
+					idx := int32(ny*w + nx)
+					t := gen.World2.Tiles[nx][ny].Type
+					if visited[idx] != search && (t == W2TileSoil || t == W2TileTransit || t == W2TileCliff) {
+						visited[idx], parent[idx], depth[idx] = search, curr, depth[curr]+1
+						queue = append(queue, idx)
+					}
+				}
+			}
+		}
+		return nil
+	}
+	// 海岸の候補 (除外されていない海岸のマス) の索引。位置は x*h+y で、全域を x, y の順に走査した時の並びと同じ
+	// タイルや除外が変わったマスと、その4近傍だけを入れ直す
+	coast := newCandidateSet(w*h, func(i int) bool {
+		x, y := i/h, i%h
+		return !gen.Excluded[y*w+x] && isCoastal(x, y)
+	})
+	refresh := func(x, y int) {
+		dxs := []int{0, 0, 1, 0, -1}
+		dys := []int{0, -1, 0, 1, 0}
+		for i := 0; i < 5; i++ {
+			nx, ny := x+dxs[i], y+dys[i]
+			if nx >= 0 && nx < w && ny >= 0 && ny < h {
+				coast.set(nx*h+ny, !gen.Excluded[ny*w+nx] && isCoastal(nx, ny))
+			}
+		}
+	}
+	applyShallow := func(cx, cy int) {
+		es := []P{}
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 { continue }
+				nx, ny := cx+dx, cy+dy
+				if nx >= 0 && nx < w && ny >= 0 && ny < h && gen.World2.Tiles[nx][ny].Type == W2TileVariableOcean {
+					es = append(es, P{nx, ny})
+				}
+			}
+		}
+		os := []P{}
+		for _, e := range es {
+			nCnt := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if dx == 0 && dy == 0 { continue }
+					nx, ny := e.x+dx, e.y+dy
+					if nx >= 0 && nx < w && ny >= 0 && ny < h && gen.World2.Tiles[nx][ny].Type == W2TileVariableOcean {
+						nCnt++
+					}
+				}
+			}
+			if nCnt >= 3 {
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if dx == 0 && dy == 0 { continue }
+						nx, ny := e.x+dx, e.y+dy
+						if nx >= 0 && nx < w && ny >= 0 && ny < h && gen.World2.Tiles[nx][ny].Type == W2TileVariableOcean {
+							os = append(os, P{nx, ny})
+						}
+					}
+				}
+			}
+		}
+		for _, p := range es { gen.World2.Tiles[p.x][p.y].Type = W2TileShallow; gen.NewSoils[p.y*w+p.x] = true; refresh(p.x, p.y) }
+		for _, p := range os { gen.World2.Tiles[p.x][p.y].Type = W2TileShallow; gen.NewSoils[p.y*w+p.x] = true; refresh(p.x, p.y) }
+	}
+
+	// Safety Loop for Cliff Gen
+	safetyLoop := 0
+	for gen.Multiplier >= 0 && safetyLoop < 10000 {
+		if gen.stopRequested() {
+			return
+		}
+		safetyLoop++
+		if coast.count == 0 { break }
+		posA := coast.pick(rng.Intn(coast.count))
+		pA := P{posA / h, posA % h}
+		rDist := 1 + rng.Intn(5)
+		candidatesB := []P{}
+		for x := pA.x - rDist; x <= pA.x+rDist; x++ {
+			for y := pA.y - rDist; y <= pA.y+rDist; y++ {
+				if x >= 0 && x < w && y >= 0 && y < h {
+					if coast.has(x*h+y) && (x != pA.x || y != pA.y) {
+						candidatesB = append(candidatesB, P{x, y})
+					}
+				}
+			}
+		}
+		if len(candidatesB) == 0 {
+			gen.Multiplier -= 0.1
+			continue
+		}
+		pB := candidatesB[rng.Intn(len(candidatesB))]
+		pathC := findPath(pA.x, pA.y, pB.x, pB.y)
+
+		if len(pathC) == 0 || len(pathC) > maxPathLen {
+			gen.Excluded[pA.y*w+pA.x] = true
+			gen.Excluded[pB.y*w+pB.x] = true
+			refresh(pA.x, pA.y)
+			refresh(pB.x, pB.y)
+			continue
+		}
+		
+		forceCliff := false
+		forceShallow := false
+		if gen.Config.ForceSwitch > 0 {
+			if gen.CliffStreak >= gen.Config.ForceSwitch { forceShallow = true } else if gen.ShallowStreak >= gen.Config.ForceSwitch { forceCliff = true }
+		}
+
+		isCliff := false
+		if forceCliff {
+			isCliff = true
+		} else if forceShallow {
+			isCliff = false
+		} else {
+			prob := float64(len(pathC)) * gen.Multiplier
+			if rng.Float64()*100.0 < prob { isCliff = true }
+		}
+
+		if isCliff {
+			for _, p := range pathC {
+				gen.World2.Tiles[p.x][p.y].Type = W2TileCliff
+				gen.Excluded[p.y*w+p.x] = true
+				gen.NewSoils[p.y*w+p.x] = true
+				refresh(p.x, p.y)
+			}
+			gen.Multiplier -= gen.Config.CliffDec
+			gen.CliffStreak++
+			gen.ShallowStreak = 0
+		} else {
+			for _, p := range pathC {
+				applyShallow(p.x, p.y)
+				gen.Excluded[p.y*w+p.x] = true
+				refresh(p.x, p.y)
+			}
+			gen.Multiplier -= gen.Config.ShallowDec
+			gen.ShallowStreak++
+			gen.CliffStreak = 0
+		}
+	}
+	if gen.Multiplier >= 0 && safetyLoop >= 10000 {
+		gen.noteSafetyCap("cliff pairs")
+	}
+}
\ No newline at end of file
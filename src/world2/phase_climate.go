@@ -0,0 +1,96 @@
+// filename: world2/phase_climate.go
+package world2
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// 気候の判定に使うしきい値
+const (
+	climateMountainElev = 0.7  // 陸地の最高標高に対してこの割合以上は山岳
+	climateTundraTemp   = 0.25 // 気温がこれ未満はツンドラ
+	climateDesertTemp   = 0.6  // 気温がこれ以上で乾燥していれば砂漠
+	climateDesertMoist  = 0.3
+	climateSwampDist    = 2 // 湖・川からこのマス数以内の低地は湿地
+	climateSwampElev    = 20
+	climateForestMoist  = 0.55 // これ以上の湿度は森林
+	climateMoistRange   = 6    // 水辺からこのマス数離れると水辺による湿り気は 0
+	climateRainBelts    = 2.8  // 緯度方向の雨の帯の周期 (赤道と高緯度が湿潤、中緯度 (lat≈0.36) が乾燥)
+	climateElevCooling  = 0.5  // 標高 ElevationMax での気温の低下量
+)
+
+// climate は (x, y) の気温と湿度 (どちらも 0..1) を返す
+// 気温は緯度 (マップの上下端が極、中央が赤道) と標高、湿度は水辺 (海・湖・川) からの距離と緯度の雨の帯で決まる
+func climate(tile World2Tile, y, h, waterDist int) (temp, moist float64) {
+	half := float64(h-1) / 2
+	lat := (float64(y) - half) / half
+	if lat < 0 {
+		lat = -lat
+	}
+	temp = 1 - lat - climateElevCooling*float64(tile.Elevation)/ElevationMax
+	if temp < 0 {
+		temp = 0
+	}
+	d := waterDist
+	if d < 0 || d > climateMoistRange {
+		d = climateMoistRange
+	}
+	rain := 0.5 + 0.5*math.Cos(lat*math.Pi*climateRainBelts)
+	moist = 0.5*(1-float64(d)/climateMoistRange) + 0.5*rain
+	return temp, moist
+}
+
+// PhaseClimate は陸地に緯度・標高・水辺からの距離でバイオームを割り当てる (水域は BiomeOcean)
+func (gen *World2Generator) PhaseClimate(w, h int, rng *rand.Rand) {
+	tiles := gen.World2.Tiles
+	land := func(t World2Tile) bool { return isLandType(t.Type) }
+	// 川は陸地だが水辺として扱う
+	waterDist := distanceField(tiles, w, h, func(t World2Tile) bool { return !land(t) || t.IsRiver }, land)
+	// 湿地は淡水 (湖・川) のそばにだけできる
+	freshDist := distanceField(tiles, w, h, func(t World2Tile) bool { return t.IsLake || t.IsRiver }, land)
+
+	maxElev := 0
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if land(tiles[x][y]) && int(tiles[x][y].Elevation) > maxElev {
+				maxElev = int(tiles[x][y].Elevation)
+			}
+		}
+	}
+	mountainElev := int(float64(maxElev) * climateMountainElev)
+
+	var counts [BiomeCount]int
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			tile := &tiles[x][y]
+			if !land(*tile) {
+				tile.Biome = BiomeOcean
+				continue
+			}
+			temp, moist := climate(*tile, y, h, waterDist[x][y])
+			elev := int(tile.Elevation)
+			switch {
+			case tile.Type == W2TileCliff || (maxElev > 0 && elev >= mountainElev):
+				tile.Biome = BiomeMountain
+			case temp < climateTundraTemp:
+				tile.Biome = BiomeTundra
+			case freshDist[x][y] > 0 && freshDist[x][y] <= climateSwampDist && elev <= climateSwampElev:
+				tile.Biome = BiomeSwamp
+			case temp >= climateDesertTemp && moist < climateDesertMoist:
+				tile.Biome = BiomeDesert
+			case moist >= climateForestMoist:
+				tile.Biome = BiomeForest
+			default:
+				tile.Biome = BiomePlains
+			}
+			counts[tile.Biome]++
+		}
+	}
+
+	gen.PhaseName = "Climate & Biomes"
+	gen.setStatsLine("Biome ", fmt.Sprintf("Biome P%d F%d D%d M%d T%d S%d",
+		counts[BiomePlains], counts[BiomeForest], counts[BiomeDesert],
+		counts[BiomeMountain], counts[BiomeTundra], counts[BiomeSwamp]))
+}
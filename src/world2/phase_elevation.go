@@ -0,0 +1,167 @@
+// filename: world2/phase_elevation.go
+package world2
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// 標高の範囲と、各要素の重み
+const (
+	ElevationMax = 255 // 陸地の標高の上限
+	SeaDepthMax  = 30  // 海と湖の深さの上限 (Elevation は -SeaDepthMax まで)
+
+	elevCoastStep    = 4  // 海岸から1マス内陸に入るごとの上昇量
+	elevCoastCap     = 40 // 海岸からの距離はこのマス数で頭打ち
+	elevWalkWeight   = 90 // ウォーカーの通過密度 (0..1) にかける重み
+	elevWalkRadius   = 2  // 通過密度をならす範囲 (半径)
+	elevCliffBonus   = 40 // 崖のタイル自体の上乗せ量
+	elevCliffFalloff = 10 // 崖から1マス離れるごとに上乗せを減らす量
+)
+
+// isLandType は標高・水系の計算で陸地として扱うタイル種別かを返す
+func isLandType(t int) bool {
+	return t == W2TileSoil || t == W2TileTransit || t == W2TileCliff
+}
+
+// distanceField は from を満たすタイルを 0 とし、through を満たすタイルだけを通る4方向の距離を返す
+// 届かないタイルは -1
+func distanceField(tiles [][]World2Tile, w, h int, from, through func(World2Tile) bool) [][]int {
+	dist := make([][]int, w)
+	type P struct{ x, y int }
+	var queue []P
+	for x := 0; x < w; x++ {
+		dist[x] = make([]int, h)
+		for y := 0; y < h; y++ {
+			dist[x][y] = -1
+			if from(tiles[x][y]) {
+				dist[x][y] = 0
+				queue = append(queue, P{x, y})
+			}
+		}
+	}
+	dxs := []int{0, 1, 0, -1}
+	dys := []int{-1, 0, 1, 0}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for i := 0; i < 4; i++ {
+			nx, ny := p.x+dxs[i], p.y+dys[i]
+			if nx >= 0 && nx < w && ny >= 0 && ny < h && dist[nx][ny] < 0 && through(tiles[nx][ny]) {
+				dist[nx][ny] = dist[p.x][p.y] + 1
+				queue = append(queue, P{nx, ny})
+			}
+		}
+	}
+	return dist
+}
+
+// walkDensity は陸地の Walks を半径 elevWalkRadius の範囲で合計し、最大値で 0..1 に正規化する
+func walkDensity(tiles [][]World2Tile, w, h int) [][]float64 {
+	// 累積和 (sum[x+1][y+1] = (0,0)-(x,y) の合計)
+	sum := make([][]int, w+1)
+	for x := range sum {
+		sum[x] = make([]int, h+1)
+	}
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			v := 0
+			if isLandType(tiles[x][y].Type) {
+				v = int(tiles[x][y].Walks)
+			}
+			sum[x+1][y+1] = v + sum[x][y+1] + sum[x+1][y] - sum[x][y]
+		}
+	}
+	clamp := func(v, hi int) int {
+		if v < 0 {
+			return 0
+		}
+		if v > hi {
+			return hi
+		}
+		return v
+	}
+	density := make([][]float64, w)
+	maxVal := 0
+	raw := make([][]int, w)
+	for x := 0; x < w; x++ {
+		raw[x] = make([]int, h)
+		x0, x1 := clamp(x-elevWalkRadius, w), clamp(x+elevWalkRadius+1, w)
+		for y := 0; y < h; y++ {
+			y0, y1 := clamp(y-elevWalkRadius, h), clamp(y+elevWalkRadius+1, h)
+			raw[x][y] = sum[x1][y1] - sum[x0][y1] - sum[x1][y0] + sum[x0][y0]
+			if raw[x][y] > maxVal {
+				maxVal = raw[x][y]
+			}
+		}
+	}
+	for x := 0; x < w; x++ {
+		density[x] = make([]float64, h)
+		if maxVal == 0 {
+			continue
+		}
+		for y := 0; y < h; y++ {
+			density[x][y] = float64(raw[x][y]) / float64(maxVal)
+		}
+	}
+	return density
+}
+
+// PhaseElevation は海岸からの距離、ウォーカーの通過密度、崖の位置から各タイルの標高を決める
+func (gen *World2Generator) PhaseElevation(w, h int, rng *rand.Rand) {
+	tiles := gen.World2.Tiles
+	land := func(t World2Tile) bool { return isLandType(t.Type) }
+	water := func(t World2Tile) bool { return !isLandType(t.Type) }
+
+	coast := distanceField(tiles, w, h, water, land)
+	shore := distanceField(tiles, w, h, land, water)
+	cliff := distanceField(tiles, w, h, func(t World2Tile) bool { return t.Type == W2TileCliff }, land)
+	density := walkDensity(tiles, w, h)
+
+	maxElev, landCount, landSum := 0, 0, 0
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if !land(tiles[x][y]) {
+				// 海と湖: 岸からの距離を深さにする (陸地がない場合は最深)
+				depth := shore[x][y]
+				if depth < 0 || depth > SeaDepthMax {
+					depth = SeaDepthMax
+				}
+				tiles[x][y].Elevation = int16(-depth)
+				continue
+			}
+
+			d := coast[x][y]
+			if d < 0 || d > elevCoastCap {
+				d = elevCoastCap
+			}
+			e := elevCoastStep*d + int(elevWalkWeight*density[x][y])
+			if c := cliff[x][y]; c >= 0 {
+				if bonus := elevCliffBonus - elevCliffFalloff*c; bonus > 0 {
+					e += bonus
+				}
+			}
+			e += rng.Intn(3)
+			if e < 1 {
+				e = 1
+			}
+			if e > ElevationMax {
+				e = ElevationMax
+			}
+			tiles[x][y].Elevation = int16(e)
+
+			landCount++
+			landSum += e
+			if e > maxElev {
+				maxElev = e
+			}
+		}
+	}
+
+	avg := 0
+	if landCount > 0 {
+		avg = landSum / landCount
+	}
+	gen.PhaseName = fmt.Sprintf("Elevation (Max %d)", maxElev)
+	gen.setStatsLine("Elev ", fmt.Sprintf("Elev Max:%d Avg:%d", maxElev, avg))
+}
@@ -0,0 +1,13 @@
+// filename: world2/phase_init.go
+package world2
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+func (gen *World2Generator) PhaseInit(w, h int, rng *rand.Rand) {
+	gen.PhaseName = fmt.Sprintf("Init (Mask Type %d/%d)", gen.Config.MainType, gen.Config.SubType)
+
+	// Phase_Init のロジック本体は InitWorld2Generator() にて実行済み
+}
\ No newline at end of file
@@ -0,0 +1,141 @@
+// filename: world2/phase_island_shallow_adjust.go
+package world2
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// islandShallowCircle は B航路の経由島 (緑の小島) の重心と平均距離から円を求める
+// 経由島が5個未満、半径が10マス未満、または中心から見て120度以上の隙間がある
+// (別々の航路の島が散らばっているだけ) 場合は円状とみなさず ok=false を返す
+func (gen *World2Generator) islandShallowCircle(w, h int) (centerX, centerY, radius float64, ok bool) {
+	type Point struct{ x, y int }
+	var routeIslands []Point
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if gen.World2.Tiles[x][y].Source == SrcBRouteIsland {
+				routeIslands = append(routeIslands, Point{x, y})
+			}
+		}
+	}
+	if len(routeIslands) < 5 {
+		return 0, 0, 0, false
+	}
+
+	for _, p := range routeIslands {
+		centerX += float64(p.x)
+		centerY += float64(p.y)
+	}
+	centerX /= float64(len(routeIslands))
+	centerY /= float64(len(routeIslands))
+
+	for _, p := range routeIslands {
+		radius += math.Hypot(float64(p.x)-centerX, float64(p.y)-centerY)
+	}
+	radius /= float64(len(routeIslands))
+
+	if radius < 10.0 {
+		return 0, 0, 0, false
+	}
+
+	angles := make([]float64, len(routeIslands))
+	for i, p := range routeIslands {
+		angles[i] = math.Atan2(float64(p.y)-centerY, float64(p.x)-centerX)
+	}
+	sort.Float64s(angles)
+	maxGap := angles[0] + 2*math.Pi - angles[len(angles)-1]
+	for i := 1; i < len(angles); i++ {
+		maxGap = math.Max(maxGap, angles[i]-angles[i-1])
+	}
+	if maxGap >= 2*math.Pi/3 {
+		return 0, 0, 0, false
+	}
+	return centerX, centerY, radius, true
+}
+
+// 円状の島の内側に浅瀬を生成する
+func (gen *World2Generator) PhaseIslandShallowAdjust(w, h int, rng *rand.Rand) {
+	if !gen.Config.IslandShallow {
+		gen.PhaseName = "Island Shallow Adjust (Skipped)"
+		return
+	}
+
+	// 1-3. B航路の経由島が作る円の中心と半径
+	centerX, centerY, radius, ok := gen.islandShallowCircle(w, h)
+	if !ok {
+		gen.PhaseName = "Island Shallow Adjust (No circle)"
+		return
+	}
+	// 浅瀬にするのは円の内側の、何も描かれていない海だけ (航路1の A/B航路は残す)
+	shallowable := func(x, y int) bool {
+		t := gen.World2.Tiles[x][y]
+		if t.Type != W2TileVariableOcean || t.Source != SrcNone {
+			return false
+		}
+		return math.Hypot(float64(x)-centerX, float64(y)-centerY) < radius
+	}
+
+	// 4. フェーズ1: 円の内側で陸地 (大陸・島・経由島・崖) に隣接する海を浅瀬化
+	dxs := []int{0, 1, 0, -1}
+	dys := []int{-1, 0, 1, 0}
+
+	firstShallows := 0
+	for x := 3; x < w-3; x++ {
+		for y := 3; y < h-3; y++ {
+			if !shallowable(x, y) {
+				continue
+			}
+			for i := 0; i < 4; i++ {
+				nt := gen.World2.Tiles[x+dxs[i]][y+dys[i]].Type
+				if nt == W2TileSoil || nt == W2TileTransit || nt == W2TileCliff {
+					gen.World2.Tiles[x][y].Type = W2TileShallow
+					gen.NewSoils[y*w+x] = true
+					firstShallows++
+					break
+				}
+			}
+		}
+	}
+
+	// 浅瀬が1つも作られなかった場合は終了
+	if firstShallows == 0 {
+		gen.PhaseName = "Island Shallow Adjust (0 shallows)"
+		return
+	}
+
+	// 5. フェーズ2: 円の内側の海ごとに、上下左右の浅瀬の数を数える
+	// (乱数を引く順序を固定するため、マップではなく走査順のスライスに溜める)
+	type Candidate struct{ x, y int }
+	var candidates []Candidate
+	for x := 3; x < w-3; x++ {
+		for y := 3; y < h-3; y++ {
+			if !shallowable(x, y) {
+				continue
+			}
+			count := 0
+			for i := 0; i < 4; i++ {
+				if gen.World2.Tiles[x+dxs[i]][y+dys[i]].Type == W2TileShallow {
+					count++
+				}
+			}
+			if count >= 2 {
+				candidates = append(candidates, Candidate{x, y})
+			}
+		}
+	}
+
+	// 6. フェーズ3: 浅瀬2つ以上に接する海を70%の確率で浅瀬化
+	secondShallows := 0
+	for _, c := range candidates {
+		if rng.Float64() < 0.7 {
+			gen.World2.Tiles[c.x][c.y].Type = W2TileShallow
+			gen.NewSoils[c.y*w+c.x] = true
+			secondShallows++
+		}
+	}
+
+	gen.PhaseName = fmt.Sprintf("Island Shallow Adjust (%d shallows)", firstShallows+secondShallows)
+}
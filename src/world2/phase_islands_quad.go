@@ -1,5 +1,5 @@
 // filename: world2/phase_islands_quad.go
-package main
+package world2
 
 import (
 	"math"
@@ -7,14 +7,14 @@ import (
 )
 
 // Phase_IslandsQuad のロジックをポーズ/再実行対応のために分離 (ポーズ処理は完全に削除)
-func (g *Game) processIslandsQuadStep(w, h int, rng *rand.Rand, gen *World2Generator) {
+func (gen *World2Generator) processIslandsQuadStep(w, h int, rng *rand.Rand) {
 	vastSize := gen.Config.VastOcean
 	boundSize := gen.Config.IslandBound
 	
 	placeSoil := func(x, y int, src int) {
 		if x >= 3 && x < w-3 && y >= 3 && y < h-3 {
-			g.World2.Tiles[x][y].Type = W2TileSoil
-			g.World2.Tiles[x][y].Source = src
+			gen.World2.Tiles[x][y].Type = W2TileSoil
+			gen.World2.Tiles[x][y].Source = src
 			gen.NewSoils[y*w+x] = true
 		}
 	}
@@ -39,10 +39,10 @@ func (g *Game) processIslandsQuadStep(w, h int, rng *rand.Rand, gen *World2Gener
 			isVast := true
 			
 			// 描画用の矩形は設定するが、ポーズはしない
-			g.World2.PinkRects = []Rect{} 
+			gen.World2.PinkRects = []Rect{} 
 			
-			vastRect := Rect{x: cx - halfVast, y: cy - halfVast, w: vastSize, h: vastSize}
-			g.World2.PinkRects = append(g.World2.PinkRects, vastRect)
+			vastRect := Rect{X: cx - halfVast, Y: cy - halfVast, W: vastSize, H: vastSize}
+			gen.World2.PinkRects = append(gen.World2.PinkRects, vastRect)
 			
 			for dy := -halfVast; dy <= halfVast; dy++ {
 				for dx := -halfVast; dx <= halfVast; dx++ {
@@ -51,7 +51,7 @@ func (g *Game) processIslandsQuadStep(w, h int, rng *rand.Rand, gen *World2Gener
 						isVast = false
 						break
 					}
-					if g.World2.Tiles[tx][ty].Type != W2TileVariableOcean {
+					if gen.World2.Tiles[tx][ty].Type != W2TileVariableOcean {
 						isVast = false
 						break
 					}
@@ -63,7 +63,7 @@ func (g *Game) processIslandsQuadStep(w, h int, rng *rand.Rand, gen *World2Gener
 				// ポーズ処理はすべて削除し、見つけたら即座に島の生成に利用
 				return cx, cy, true 
 			} else {
-				g.World2.PinkRects = []Rect{}
+				gen.World2.PinkRects = []Rect{}
 			}
 		}
 		return 0, 0, false
@@ -109,13 +109,13 @@ func (g *Game) processIslandsQuadStep(w, h int, rng *rand.Rand, gen *World2Gener
 			quadrants[idx] = quadrants[len(quadrants)-1]
 			quadrants = quadrants[:len(quadrants)-1]
 		}
-		g.World2.PinkRects = []Rect{} 
+		gen.World2.PinkRects = []Rect{} 
 	}
 
 	// ループが完了したら次のフェーズへ
-	gen.PhaseName = "6. Islands (Quad)"
+	gen.PhaseName = "Islands (Quad)"
 }
 
-func (g *Game) PhaseIslandsQuad(w, h int, rng *rand.Rand, gen *World2Generator) {
-	g.processIslandsQuadStep(w, h, rng, gen)
+func (gen *World2Generator) PhaseIslandsQuad(w, h int, rng *rand.Rand) {
+	gen.processIslandsQuadStep(w, h, rng)
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+// filename: world2/phase_islands_rand.go
+package world2
+
+import (
+	"math/rand"
+)
+
+func (gen *World2Generator) PhaseIslandsRand(w, h int, rng *rand.Rand) {
+	gen.PhaseName = "Islands (Random)"
+	
+	for k := 0; k < 5; k++ {
+		rx, ry := rng.Intn(w), rng.Intn(h)
+		if gen.World2.Tiles[rx][ry].Type == W2TileVariableOcean {
+			gen.World2.Tiles[rx][ry].Type = W2TileSoil
+			gen.World2.Tiles[rx][ry].Source = SrcIsland
+			gen.NewSoils[ry*w+rx] = true
+		}
+	}
+}
\ No newline at end of file
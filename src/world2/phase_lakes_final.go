@@ -1,21 +1,30 @@
 // filename: world2/phase_lakes_final.go
-package main
+package world2
 
 import (
 	"fmt"
 	"math/rand"
 )
 
-func (g *Game) PhaseLakesFinal(w, h int, rng *rand.Rand, gen *World2Generator) {
-	gen.CurrentStep++ // 23
-	gen.PhaseName = "10. Lakes & Done"
+func (gen *World2Generator) PhaseLakesFinal(w, h int, rng *rand.Rand) {
+	gen.PhaseName = "Lakes"
+	counts := gen.markLakes(w, h)
+	gen.World2.StatsInfo = []string{
+		fmt.Sprintf("Phase: %s", gen.PhaseName),
+		fmt.Sprintf("Soil:%d Cliff:%d", counts[W2TileSoil], counts[W2TileCliff]),
+		fmt.Sprintf("Lake:%d Shlw:%d", counts[-1], counts[W2TileShallow]),
+	}
+}
+
+// markLakes は外周の固定海から陸地を通らずに届かない海と浅瀬を湖にし、タイルの種類ごとの数を返す (湖は -1)
+func (gen *World2Generator) markLakes(w, h int) map[int]int {
 	reached := make([][]bool, w)
 	for x := range reached { reached[x] = make([]bool, h) }
 	type P struct { x, y int }
 	queue := []P{}
 	for x := 0; x < w; x++ {
 		for y := 0; y < h; y++ {
-			if g.World2.Tiles[x][y].Type == W2TileFixedOcean {
+			if gen.World2.Tiles[x][y].Type == W2TileFixedOcean {
 				reached[x][y] = true
 				queue = append(queue, P{x, y})
 			}
@@ -29,7 +38,7 @@ func (g *Game) PhaseLakesFinal(w, h int, rng *rand.Rand, gen *World2Generator) {
 		for i := 0; i < 4; i++ {
 			nx, ny := p.x+dx[i], p.y+dy[i]
 			if nx >= 0 && nx < w && ny >= 0 && ny < h {
-				t := g.World2.Tiles[nx][ny].Type
+				t := gen.World2.Tiles[nx][ny].Type
 				isLand := (t == W2TileSoil || t == W2TileTransit || t == W2TileCliff)
 				if !reached[nx][ny] && !isLand {
 					reached[nx][ny] = true
@@ -41,11 +50,11 @@ func (g *Game) PhaseLakesFinal(w, h int, rng *rand.Rand, gen *World2Generator) {
 	counts := make(map[int]int)
 	for x := 0; x < w; x++ {
 		for y := 0; y < h; y++ {
-			t := g.World2.Tiles[x][y].Type
-			g.World2.Tiles[x][y].IsLake = false
+			t := gen.World2.Tiles[x][y].Type
+			gen.World2.Tiles[x][y].IsLake = false
 			if t == W2TileVariableOcean || t == W2TileShallow {
 				if !reached[x][y] {
-					g.World2.Tiles[x][y].IsLake = true
+					gen.World2.Tiles[x][y].IsLake = true
 					counts[-1]++
 				} else {
 					counts[t]++
@@ -55,10 +64,5 @@ func (g *Game) PhaseLakesFinal(w, h int, rng *rand.Rand, gen *World2Generator) {
 			}
 		}
 	}
-	g.World2.StatsInfo = []string{
-		fmt.Sprintf("Phase: %s", gen.PhaseName),
-		fmt.Sprintf("Soil:%d Cliff:%d", counts[W2TileSoil], counts[W2TileCliff]),
-		fmt.Sprintf("Lake:%d Shlw:%d", counts[-1], counts[W2TileShallow]),
-	}
-	gen.IsFinished = true
+	return counts
 }
\ No newline at end of file
@@ -0,0 +1,48 @@
+// filename: world2/phase_mask_gen.go
+package world2
+
+import (
+	"fmt"
+	"math/rand"
+	"math"
+)
+
+func (gen *World2Generator) PhaseMaskGen(w, h int, rng *rand.Rand) {
+	gen.MaskMain = GenerateMask(w, h, gen.Config.MainType, rng)
+	gen.MaskSub = GenerateMask(w, h, gen.Config.SubType, rng)
+	gen.FinalMask = BlendMasks(gen.MaskMain, gen.MaskSub, gen.Config.Ratio)
+
+	minP, maxP := gen.Config.MinPct, gen.Config.MaxPct
+	if minP > maxP { minP, maxP = maxP, minP }
+	targetPct := minP
+	if maxP > minP { targetPct = minP + rng.Intn(maxP-minP+1) }
+	gen.TargetSoilCount = int(math.Round(float64(w*h) * float64(targetPct) / 100.0))
+	gen.LastTargetSoil = targetPct
+	gen.PhaseName = fmt.Sprintf("Mask (Target Soil %d%%)", targetPct)
+}
+
+// MaskSource は (x, y) で支配的だったマスクから土の Source を決める
+// Main/Sub の寄与 (マスク値 × 比率) のどちらかが 2/3 以上なら SrcMain/SrcSub、拮抗していれば SrcMix
+func (gen *World2Generator) MaskSource(x, y int) int {
+	if gen.MaskMain == nil || gen.MaskSub == nil {
+		return SrcMain
+	}
+	r := float64(gen.Config.Ratio) / 10.0
+	mainW := gen.MaskMain[x][y] * r
+	subW := gen.MaskSub[x][y] * (1 - r)
+	if mainW+subW <= 0 {
+		// どちらのマスクも0の地点は比率の大きい側に帰属させる
+		if r >= 0.5 {
+			return SrcMain
+		}
+		return SrcSub
+	}
+	share := mainW / (mainW + subW)
+	switch {
+	case share >= 2.0/3.0:
+		return SrcMain
+	case share <= 1.0/3.0:
+		return SrcSub
+	}
+	return SrcMix
+}
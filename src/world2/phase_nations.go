@@ -0,0 +1,165 @@
+// filename: world2/phase_nations.go
+package world2
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// 首都の選び方
+const (
+	MaxNations = 32 // NationCount の上限 (Nation は 1..MaxNations)
+
+	capitalCoastCap  = 10 // 海岸からの距離による重みはこのマス数で頭打ち
+	capitalCoastMin  = 2  // 海岸からこのマス数未満の土には首都を置かない
+	capitalCliffNear = 3  // 崖からこのマス数以内は重みを下げる
+)
+
+// isNationPassable は国の勢力圏を広げる時に通れるタイルかを返す (陸地と、航路の海)
+func isNationPassable(t World2Tile) bool {
+	if isLandType(t.Type) {
+		return true
+	}
+	return t.Type == W2TileVariableOcean &&
+		(t.Source == SrcTransitPath || t.Source == SrcBRoutePath || t.Source == SrcRoute2Path)
+}
+
+// pickCapitals は海岸と崖から離れた土ほど選ばれやすい重みで、互いに離れた首都を最大 n 個選ぶ
+func pickCapitals(tiles [][]World2Tile, w, h, n int, rng *rand.Rand) []Point {
+	land := func(t World2Tile) bool { return isLandType(t.Type) }
+	coast := distanceField(tiles, w, h, func(t World2Tile) bool { return !land(t) }, land)
+	cliff := distanceField(tiles, w, h, func(t World2Tile) bool { return t.Type == W2TileCliff }, land)
+
+	type cand struct {
+		p      Point
+		weight int
+	}
+	var cands []cand
+	landCount := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !land(tiles[x][y]) {
+				continue
+			}
+			landCount++
+			d := coast[x][y]
+			if tiles[x][y].Type != W2TileSoil || d < capitalCoastMin {
+				continue
+			}
+			if d > capitalCoastCap {
+				d = capitalCoastCap
+			}
+			weight := d * d
+			if c := cliff[x][y]; c >= 0 && c <= capitalCliffNear {
+				weight /= 4
+			}
+			cands = append(cands, cand{Point{x, y}, weight + 1})
+		}
+	}
+
+	// 首都どうしは、陸地を n 等分した円の半径程度は離す
+	spacing := 0
+	for spacing*spacing*3*n < landCount {
+		spacing++
+	}
+
+	var capitals []Point
+	for len(capitals) < n {
+		total := 0
+		for _, c := range cands {
+			total += c.weight
+		}
+		if total == 0 {
+			break
+		}
+		r := rng.Intn(total)
+		var pick Point
+		for _, c := range cands {
+			if r < c.weight {
+				pick = c.p
+				break
+			}
+			r -= c.weight
+		}
+		capitals = append(capitals, pick)
+
+		kept := cands[:0]
+		for _, c := range cands {
+			dx, dy := c.p.X-pick.X, c.p.Y-pick.Y
+			if dx*dx+dy*dy >= spacing*spacing {
+				kept = append(kept, c)
+			}
+		}
+		cands = kept
+	}
+	return capitals
+}
+
+// assignNations は首都から陸地と航路をたどる距離で、各陸地タイルを最も近い首都の国 (1..) にする
+// 首都から陸続きでも航路でもつながらない陸地は 0 (無所属) のまま
+func assignNations(tiles [][]World2Tile, w, h int, capitals []Point) {
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			tiles[x][y].Nation = 0
+		}
+	}
+	owner := make([][]uint8, w)
+	for x := range owner {
+		owner[x] = make([]uint8, h)
+	}
+	var queue []Point
+	for i, c := range capitals {
+		owner[c.X][c.Y] = uint8(i + 1)
+		queue = append(queue, c)
+	}
+	dxs := []int{0, 1, 0, -1}
+	dys := []int{-1, 0, 1, 0}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for i := 0; i < 4; i++ {
+			nx, ny := p.X+dxs[i], p.Y+dys[i]
+			if nx >= 0 && nx < w && ny >= 0 && ny < h && owner[nx][ny] == 0 && isNationPassable(tiles[nx][ny]) {
+				owner[nx][ny] = owner[p.X][p.Y]
+				queue = append(queue, Point{nx, ny})
+			}
+		}
+	}
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if isLandType(tiles[x][y].Type) {
+				tiles[x][y].Nation = owner[x][y]
+			}
+		}
+	}
+}
+
+// PhaseNations は首都を選び、陸地を国に分ける (国境は陸地と航路をたどる距離のボロノイ分割)
+func (gen *World2Generator) PhaseNations(w, h int, rng *rand.Rand) {
+	n := gen.Config.NationCount
+	if n <= 0 {
+		gen.World2.Capitals = nil
+		assignNations(gen.World2.Tiles, w, h, nil)
+		gen.PhaseName = "Nations (Skipped)"
+		return
+	}
+	capitals := pickCapitals(gen.World2.Tiles, w, h, n, rng)
+	gen.World2.Capitals = capitals
+	assignNations(gen.World2.Tiles, w, h, capitals)
+
+	sizes := make([]int, len(capitals)+1)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if isLandType(gen.World2.Tiles[x][y].Type) {
+				sizes[gen.World2.Tiles[x][y].Nation]++
+			}
+		}
+	}
+	parts := make([]string, len(capitals))
+	for i := range capitals {
+		parts[i] = fmt.Sprint(sizes[i+1])
+	}
+	gen.PhaseName = fmt.Sprintf("Nations (%d)", len(capitals))
+	gen.setStatsLine("Nation ", fmt.Sprintf("Nation %s Free:%d", strings.Join(parts, "/"), sizes[0]))
+}
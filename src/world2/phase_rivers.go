@@ -0,0 +1,157 @@
+// filename: world2/phase_rivers.go
+package world2
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// 川の水源の選び方
+const (
+	riverSourceTop     = 0.25 // 標高の高い方からこの割合の土を水源の候補にする
+	riverSourceSpacing = 6    // 水源どうし (と既存の川) の最小距離 (マス)
+)
+
+// isRiverSink は川が流れ込める水域 (海・浅瀬・湖) かを返す
+func isRiverSink(t World2Tile) bool {
+	return t.Type == W2TileVariableOcean || t.Type == W2TileShallow || t.IsLake
+}
+
+// floodItem は priority-flood のキューの要素 (level が低い順、同じなら idx 順)
+type floodItem struct {
+	level int
+	idx   int
+}
+
+type floodQueue []floodItem
+
+func (q floodQueue) Len() int { return len(q) }
+func (q floodQueue) Less(i, j int) bool {
+	if q[i].level != q[j].level {
+		return q[i].level < q[j].level
+	}
+	return q[i].idx < q[j].idx
+}
+func (q floodQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *floodQueue) Push(x interface{}) { *q = append(*q, x.(floodItem)) }
+func (q *floodQueue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+// drainage は水域から土のタイルへ priority-flood を広げ、各土のタイルの流れ先 (y*w+x) を返す
+// 流れ先をたどると必ず水域に着く。窪地は水が溜まる高さまで埋めたものとして扱う
+// 水域に届かない土のタイルは -1
+func drainage(tiles [][]World2Tile, w, h int) []int {
+	down := make([]int, w*h)
+	for i := range down {
+		down[i] = -1
+	}
+	done := make([]bool, w*h)
+	q := &floodQueue{}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if isRiverSink(tiles[x][y]) {
+				done[y*w+x] = true
+				heap.Push(q, floodItem{level: int(tiles[x][y].Elevation), idx: y*w + x})
+			}
+		}
+	}
+	dxs := []int{0, 1, 0, -1}
+	dys := []int{-1, 0, 1, 0}
+	for q.Len() > 0 {
+		it := heap.Pop(q).(floodItem)
+		x, y := it.idx%w, it.idx/w
+		for i := 0; i < 4; i++ {
+			nx, ny := x+dxs[i], y+dys[i]
+			if nx < 0 || nx >= w || ny < 0 || ny >= h || tiles[nx][ny].Type != W2TileSoil {
+				continue
+			}
+			n := ny*w + nx
+			if done[n] {
+				continue
+			}
+			done[n] = true
+			down[n] = it.idx
+			level := int(tiles[nx][ny].Elevation)
+			if level < it.level {
+				level = it.level
+			}
+			heap.Push(q, floodItem{level: level, idx: n})
+		}
+	}
+	return down
+}
+
+// PhaseRivers は標高の高い土から水域まで、流れ先をたどって川を引く
+func (gen *World2Generator) PhaseRivers(w, h int, rng *rand.Rand) {
+	if gen.Config.RiverCount <= 0 {
+		gen.PhaseName = "Rivers (Skipped)"
+		return
+	}
+	tiles := gen.World2.Tiles
+	down := drainage(tiles, w, h)
+
+	// 水源の候補: 水域に流れ出せる土のうち、標高の高いもの
+	var cands []int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if tiles[x][y].Type == W2TileSoil && down[y*w+x] >= 0 {
+				cands = append(cands, y*w+x)
+			}
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return tiles[cands[i]%w][cands[i]/w].Elevation > tiles[cands[j]%w][cands[j]/w].Elevation
+	})
+	cands = cands[:int(float64(len(cands))*riverSourceTop)]
+	rng.Shuffle(len(cands), func(i, j int) { cands[i], cands[j] = cands[j], cands[i] })
+
+	nearRiver := func(idx int) bool {
+		cx, cy := idx%w, idx/w
+		for x := cx - riverSourceSpacing; x <= cx+riverSourceSpacing; x++ {
+			for y := cy - riverSourceSpacing; y <= cy+riverSourceSpacing; y++ {
+				if x >= 0 && x < w && y >= 0 && y < h && tiles[x][y].IsRiver {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	rivers, riverTiles := 0, 0
+	for _, src := range cands {
+		if rivers >= gen.Config.RiverCount {
+			break
+		}
+		if nearRiver(src) {
+			continue
+		}
+		// 流れ先をたどり、水域か既存の川 (支流として合流) に着いたら終わり
+		var path []int
+		for i := src; i >= 0 && !isRiverSink(tiles[i%w][i/w]); i = down[i] {
+			path = append(path, i)
+			if tiles[i%w][i/w].IsRiver {
+				break
+			}
+		}
+		if len(path) < gen.Config.RiverMinLen {
+			continue
+		}
+		for _, i := range path {
+			if !tiles[i%w][i/w].IsRiver {
+				tiles[i%w][i/w].IsRiver = true
+				gen.NewSoils[i] = true
+				riverTiles++
+			}
+		}
+		rivers++
+	}
+
+	gen.PhaseName = fmt.Sprintf("Rivers (%d)", rivers)
+	gen.setStatsLine("River ", fmt.Sprintf("River %d: %d tiles", rivers, riverTiles))
+}
@@ -0,0 +1,276 @@
+// filename: world2/phase_settlements.go
+package world2
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// 集落の置き方
+const (
+	settleSpacing = 7  // 集落どうしの最小距離 (マス)
+	portSpacing   = 10 // 港どうしの最小距離 (マス)
+
+	settleScoreCoast   = 2 // 海に面した土
+	settleScoreRiver   = 1 // 川のそば
+	settleScoreMouth   = 6 // 河口 (海に面した川)。都市になる
+	settleScoreTransit = 3 // 経由島・孤立島
+)
+
+// 道を引く時の1マスあたりのコスト (-1 は通れない)
+const (
+	roadCostRoad    = 1  // 既存の道
+	roadCostLand    = 3  // 土・経由島
+	roadCostBridge  = 5  // 川 (橋を架ける)
+	roadCostFord    = 10 // 浅瀬 (渡し)
+	roadCostCliff   = 20 // 崖
+	roadCostBlocked = -1
+)
+
+// isSeaRoute は航路 (A/B航路・航路2) の海かを返す
+func isSeaRoute(t World2Tile) bool {
+	return t.Type == W2TileVariableOcean &&
+		(t.Source == SrcTransitPath || t.Source == SrcBRoutePath || t.Source == SrcRoute2Path)
+}
+
+// isSea は湖以外の水域かを返す
+func isSea(t World2Tile) bool {
+	return !isLandType(t.Type) && !t.IsLake
+}
+
+// roadCost は道が tile に入る時のコストを返す
+func roadCost(t World2Tile) int {
+	switch {
+	case t.IsRoad:
+		return roadCostRoad
+	case t.Type == W2TileSoil && t.IsRiver:
+		return roadCostBridge
+	case t.Type == W2TileSoil || t.Type == W2TileTransit:
+		return roadCostLand
+	case t.Type == W2TileShallow && !t.IsLake:
+		return roadCostFord
+	case t.Type == W2TileCliff:
+		return roadCostCliff
+	}
+	return roadCostBlocked
+}
+
+// settleScore は (x, y) に町を置く重みと、都市になるかを返す (0 は置けない)
+func settleScore(tiles [][]World2Tile, w, h, x, y int) (score int, city bool) {
+	t := tiles[x][y]
+	if t.Type != W2TileSoil && t.Type != W2TileTransit {
+		return 0, false
+	}
+	coast, river := false, false
+	for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+		nx, ny := x+d.X, y+d.Y
+		if nx < 0 || nx >= w || ny < 0 || ny >= h {
+			continue
+		}
+		if isSea(tiles[nx][ny]) {
+			coast = true
+		}
+		if tiles[nx][ny].IsRiver {
+			river = true
+		}
+	}
+	switch {
+	case t.IsRiver && coast:
+		return settleScoreMouth, true
+	case t.IsRiver:
+		return 0, false
+	}
+	if coast {
+		score += settleScoreCoast
+	}
+	if river {
+		score += settleScoreRiver
+	}
+	if t.Type == W2TileTransit || t.Source == SrcBRouteIsland || t.Source == SrcIsland {
+		score += settleScoreTransit
+	}
+	return score, false
+}
+
+// buildRoad は from から既存の道網 (network) のどこかまで、roadCost の合計が最小の道を引く
+// 道網に届かなければ何もせず false を返す。引いた道のタイルは network に加わる
+func buildRoad(tiles [][]World2Tile, w, h int, from Point, network []bool) (int, bool) {
+	dist := make([]int, w*h)
+	prev := make([]int, w*h)
+	for i := range dist {
+		dist[i] = math.MaxInt32
+		prev[i] = -1
+	}
+	start := from.Y*w + from.X
+	dist[start] = 0
+	q := &floodQueue{{level: 0, idx: start}}
+	goal := -1
+	for q.Len() > 0 {
+		it := heap.Pop(q).(floodItem)
+		if it.level > dist[it.idx] {
+			continue
+		}
+		if network[it.idx] {
+			goal = it.idx
+			break
+		}
+		x, y := it.idx%w, it.idx/w
+		for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+			nx, ny := x+d.X, y+d.Y
+			if nx < 0 || nx >= w || ny < 0 || ny >= h {
+				continue
+			}
+			c := roadCost(tiles[nx][ny])
+			n := ny*w + nx
+			if c == roadCostBlocked || it.level+c >= dist[n] {
+				continue
+			}
+			dist[n] = it.level + c
+			prev[n] = it.idx
+			heap.Push(q, floodItem{level: dist[n], idx: n})
+		}
+	}
+	if goal < 0 {
+		return 0, false
+	}
+	laid := 0
+	for i := goal; i >= 0; i = prev[i] {
+		if !tiles[i%w][i/w].IsRoad {
+			tiles[i%w][i/w].IsRoad = true
+			laid++
+		}
+		network[i] = true
+	}
+	return laid, true
+}
+
+// PhaseSettlements は首都・港・町と都市を置き、道でつなぐ
+// 港は航路が陸地に接する所、町は海岸・河口・経由島ほど置かれやすい (河口は都市)
+func (gen *World2Generator) PhaseSettlements(w, h int, rng *rand.Rand) {
+	tiles := gen.World2.Tiles
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			tiles[x][y].Settlement = SettleNone
+			tiles[x][y].IsRoad = false
+		}
+	}
+	if gen.Config.TownCount <= 0 {
+		gen.PhaseName = "Settlements (Skipped)"
+		return
+	}
+
+	var settles []Point
+	farFrom := func(p Point, spacing int, kind uint8) bool {
+		for _, s := range settles {
+			if kind != SettleNone && tiles[s.X][s.Y].Settlement != kind {
+				continue
+			}
+			dx, dy := s.X-p.X, s.Y-p.Y
+			if dx*dx+dy*dy < spacing*spacing {
+				return false
+			}
+		}
+		return true
+	}
+	place := func(p Point, kind uint8) {
+		tiles[p.X][p.Y].Settlement = kind
+		gen.NewSoils[p.Y*w+p.X] = true
+		settles = append(settles, p)
+	}
+
+	// 首都は都市
+	for _, c := range gen.World2.Capitals {
+		if c != NoCapital {
+			place(c, SettleCity)
+		}
+	}
+
+	// 港: 航路に接する陸地
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			t := tiles[x][y]
+			if (t.Type != W2TileSoil && t.Type != W2TileTransit) || t.Settlement != SettleNone {
+				continue
+			}
+			touches := false
+			for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+				nx, ny := x+d.X, y+d.Y
+				if nx >= 0 && nx < w && ny >= 0 && ny < h && isSeaRoute(tiles[nx][ny]) {
+					touches = true
+				}
+			}
+			if touches && farFrom(Point{x, y}, portSpacing, SettlePort) {
+				place(Point{x, y}, SettlePort)
+			}
+		}
+	}
+
+	// 町と都市: 重み付きの抽選で、既存の集落から離れた所に置く
+	type cand struct {
+		p      Point
+		weight int
+		city   bool
+	}
+	var cands []cand
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if tiles[x][y].Settlement != SettleNone {
+				continue
+			}
+			if score, city := settleScore(tiles, w, h, x, y); score > 0 {
+				cands = append(cands, cand{Point{x, y}, score, city})
+			}
+		}
+	}
+	for towns := 0; towns < gen.Config.TownCount; towns++ {
+		kept := cands[:0]
+		total := 0
+		for _, c := range cands {
+			if farFrom(c.p, settleSpacing, SettleNone) {
+				kept = append(kept, c)
+				total += c.weight
+			}
+		}
+		cands = kept
+		if total == 0 {
+			break
+		}
+		r := rng.Intn(total)
+		for _, c := range cands {
+			if r < c.weight {
+				if c.city {
+					place(c.p, SettleCity)
+				} else {
+					place(c.p, SettleTown)
+				}
+				break
+			}
+			r -= c.weight
+		}
+	}
+
+	// 道: 置いた順に、それまでの道網へ最小コストでつなぐ (つながらない島では新しい道網を始める)
+	network := make([]bool, w*h)
+	roads := 0
+	for _, s := range settles {
+		if laid, ok := buildRoad(tiles, w, h, s, network); ok {
+			roads += laid
+		}
+		network[s.Y*w+s.X] = true
+	}
+	for i, on := range network {
+		if on && tiles[i%w][i/w].IsRoad {
+			gen.NewSoils[i] = true
+		}
+	}
+
+	var counts [4]int
+	for _, s := range settles {
+		counts[tiles[s.X][s.Y].Settlement]++
+	}
+	gen.PhaseName = fmt.Sprintf("Settlements (%d)", len(settles))
+	gen.setStatsLine("Settle ", fmt.Sprintf("Settle City:%d Town:%d Port:%d Road:%d",
+		counts[SettleCity], counts[SettleTown], counts[SettlePort], roads))
+}
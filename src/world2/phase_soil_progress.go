@@ -1,5 +1,5 @@
-// filename: phase_soil_progress.go
-package main
+// filename: world2/phase_soil_progress.go
+package world2
 
 import (
 	"math"
@@ -7,7 +7,7 @@ import (
 	"fmt"
 )
 
-func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator) {
+func (gen *World2Generator) PhaseSoilProgress(w, h int, rng *rand.Rand) {
 	// Soil Progress Block (Phase_SoilStart, 3, 4, 5, 6, 7, 8, 9, 10, Phase_SoilProgressEnd)
 	
 	stepIndex := gen.CurrentStep - 1
@@ -16,12 +16,12 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 
 	placeSoil := func(x, y int, srcOverride int) bool {
 		if x >= 3 && x < w-3 && y >= 3 && y < h-3 {
-			if g.World2.Tiles[x][y].Type == W2TileVariableOcean {
-				g.World2.Tiles[x][y].Type = W2TileSoil
+			if gen.World2.Tiles[x][y].Type == W2TileVariableOcean {
+				gen.World2.Tiles[x][y].Type = W2TileSoil
 				if srcOverride != SrcNone {
-					g.World2.Tiles[x][y].Source = srcOverride
+					gen.World2.Tiles[x][y].Source = srcOverride
 				} else {
-					g.World2.Tiles[x][y].Source = SrcMain 
+					gen.World2.Tiles[x][y].Source = gen.MaskSource(x, y)
 				}
 				gen.NewSoils[y*w+x] = true
 				return true
@@ -30,7 +30,30 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 		return false
 	}
 	
+	// 合成マスクが Type 1 一色でなければマスク値 > 0.6 の地点からスポーンする (仕様 Step 2)
+	// マスクがまだない (マスクの段を通っていない) 時は、クラシックと同じく中央付近から
+	var spawnPoints []struct{ x, y int }
+	if !gen.Config.ClassicOnly() && gen.FinalMask != nil {
+		for x := 3; x < w-3; x++ {
+			for y := 3; y < h-3; y++ {
+				if gen.FinalMask[x][y] > 0.6 {
+					spawnPoints = append(spawnPoints, struct{ x, y int }{x, y})
+				}
+			}
+		}
+	}
+
 	findSpawn := func() (int, int) {
+		// 既に土になったスポーン地点は候補から外す (すべて土になったらクラシックと同じく中央付近から)
+		for len(spawnPoints) > 0 {
+			i := rng.Intn(len(spawnPoints))
+			p := spawnPoints[i]
+			if gen.World2.Tiles[p.x][p.y].Type == W2TileVariableOcean {
+				return p.x, p.y
+			}
+			spawnPoints[i] = spawnPoints[len(spawnPoints)-1]
+			spawnPoints = spawnPoints[:len(spawnPoints)-1]
+		}
 		cx, cy := w/2, h/2
 		if gen.CurrentStep == Phase_SoilStart { // Phase_SoilStart (2) のみ狭い範囲
 			return cx + rng.Intn(10)-5, cy + rng.Intn(10)-5
@@ -55,11 +78,34 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 		}
 	}
 
+	// マスク指定時、既存の土の上を往復し続けるウォーカーは別のスポーン地点へ移す
+	// 移せるスポーン地点がなければ、マスクの高い所へ引き戻されないよう無作為に歩かせる
+	const stallLimit = 20
+	stalled := make([]int, len(gen.Walkers))
+
 	for gen.CurrentSoilCount < target && safety < 500000 {
+		if gen.stopRequested() {
+			return
+		}
 		safety++
 		for i := range gen.Walkers {
+			// 目標に達したら残りのウォーカーは動かさない (目標を超えて土を置かない)
+			if gen.CurrentSoilCount >= target {
+				break
+			}
+			if wx, wy := gen.Walkers[i].x, gen.Walkers[i].y; wx >= 0 && wx < w && wy >= 0 && wy < h && gen.World2.Tiles[wx][wy].Walks < math.MaxUint16 {
+				gen.World2.Tiles[wx][wy].Walks++
+			}
 			if placeSoil(gen.Walkers[i].x, gen.Walkers[i].y, SrcNone) {
 				gen.CurrentSoilCount++
+				stalled[i] = 0
+			} else {
+				stalled[i]++
+				if stalled[i] >= stallLimit && len(spawnPoints) > 0 {
+					stalled[i] = 0
+					gen.Walkers[i].x, gen.Walkers[i].y = findSpawn()
+					continue
+				}
 			}
 
 			dir := rng.Intn(4)
@@ -70,7 +116,7 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 			for d := 0; d < 4; d++ {
 				nx, ny := gen.Walkers[i].x+dxs[d], gen.Walkers[i].y+dys[d]
 				score := 0.0
-				if nx >= 0 && nx < w && ny >= 0 && ny < h {
+				if nx >= 0 && nx < w && ny >= 0 && ny < h && gen.FinalMask != nil {
 					score = gen.FinalMask[nx][ny]
 				}
 				score += rng.Float64() * 0.5
@@ -80,6 +126,10 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 				}
 			}
 
+			if stalled[i] >= stallLimit {
+				bestDir = dir
+			}
+
 			if bestScore < 0.1 || gen.Walkers[i].x < 3 || gen.Walkers[i].x >= w-3 || gen.Walkers[i].y < 3 || gen.Walkers[i].y >= h-3 {
 				nx, ny := findSpawn()
 				gen.Walkers[i].x, gen.Walkers[i].y = nx, ny
@@ -89,7 +139,10 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 			}
 		}
 	}
-	gen.PhaseName = fmt.Sprintf("3. Soil Progress: %d%%", int(milestone*100))
+	if gen.CurrentSoilCount < target {
+		gen.noteSafetyCap("soil walkers")
+	}
+	gen.PhaseName = fmt.Sprintf("Soil Progress: %d%%", int(milestone*100))
 
 	// Tectonic Shift at ~30% (Step 4)
 	if gen.CurrentStep == 4 { 
@@ -99,16 +152,17 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 		for x := 0; x < w; x++ {
 			tempGrid[x] = make([]World2Tile, h)
 			for y := 0; y < h; y++ {
-				tempGrid[x][y] = g.World2.Tiles[x][y]
+				tempGrid[x][y] = gen.World2.Tiles[x][y]
 				if tempGrid[x][y].Type == W2TileSoil {
 					tempGrid[x][y].Type = W2TileVariableOcean
+					tempGrid[x][y].Walks = 0
 				}
 			}
 		}
 		colX, colY := false, false
 		for x := 0; x < w; x++ {
 			for y := 0; y < h; y++ {
-				if g.World2.Tiles[x][y].Type == W2TileSoil {
+				if gen.World2.Tiles[x][y].Type == W2TileSoil {
 					nx := x + shiftX
 					if nx < 3 || nx >= w-3 {
 						colX = true
@@ -121,7 +175,7 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 		}
 		for x := 0; x < w; x++ {
 			for y := 0; y < h; y++ {
-				if g.World2.Tiles[x][y].Type == W2TileSoil {
+				if gen.World2.Tiles[x][y].Type == W2TileSoil {
 					ny := y + shiftY
 					if ny < 3 || ny >= h-3 {
 						colY = true
@@ -134,16 +188,25 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 		}
 		for x := 0; x < w; x++ {
 			for y := 0; y < h; y++ {
-				if g.World2.Tiles[x][y].Type == W2TileSoil {
+				if gen.World2.Tiles[x][y].Type == W2TileSoil {
 					nx, ny := x + shiftX, y + shiftY
 					if nx >= 3 && nx < w-3 && ny >= 3 && ny < h-3 {
-						tempGrid[nx][ny] = g.World2.Tiles[x][y]
+						tempGrid[nx][ny] = gen.World2.Tiles[x][y]
 						gen.NewSoils[ny*w+nx] = true
 					}
 				}
 			}
 		}
-		g.World2.Tiles = tempGrid
+		gen.World2.Tiles = tempGrid
+		// 端からはみ出して消えた土の分は、以降の段で置き直す
+		gen.CurrentSoilCount = 0
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				if tempGrid[x][y].Type == W2TileSoil {
+					gen.CurrentSoilCount++
+				}
+			}
+		}
 		for i := range gen.Walkers {
 			gen.Walkers[i].x += shiftX
 			gen.Walkers[i].y += shiftY
@@ -152,6 +215,6 @@ func (g *Game) PhaseSoilProgress(w, h int, rng *rand.Rand, gen *World2Generator)
 			if gen.Walkers[i].y < 3 { gen.Walkers[i].y = 3 }
 			if gen.Walkers[i].y >= h-3 { gen.Walkers[i].y = h - 4 }
 		}
-		gen.PhaseName += " + Tectonic"
+		gen.PhaseName += fmt.Sprintf(" + Tectonic (%+d,%+d)", shiftX, shiftY)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,251 @@
+// filename: world2/phase_transit_route1.go
+package world2
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// route1ArcMinRun 以上まっすぐ続く航路は円弧に描き直す
+const route1ArcMinRun = 5
+
+// isRoute1Tile は航路1 (A/B航路と経由島) を構成するタイルかを返す
+func isRoute1Tile(t World2Tile) bool {
+	if t.Type == W2TileTransit {
+		return true
+	}
+	return t.Type == W2TileVariableOcean && (t.Source == SrcTransitPath || t.Source == SrcBRoutePath)
+}
+
+// 航路1のマークアップと円形航路のロジック
+func (gen *World2Generator) PhaseTransitRoute1(w, h int, rng *rand.Rand) {
+	// 1. 円形航路: 直線が5マス以上続く A/B航路を円弧に置き換える
+	arcs := gen.rewriteStraightRoutes(w, h, rng)
+
+	// 2. 孤立島ごとに、接している航路のタイル数を実測する
+	gen.Route1, gen.TotalRoute1Dist = gen.measureRoute1(w, h)
+
+	gen.PhaseName = fmt.Sprintf("Transit Route 1 (%d islands, %.0f tiles, %d arcs)", len(gen.Route1), gen.TotalRoute1Dist, arcs)
+}
+
+// rewriteStraightRoutes は縦・横・斜めに route1ArcMinRun マス以上続く航路を円弧に置き換え、置き換えた数を返す
+func (gen *World2Generator) rewriteStraightRoutes(w, h int, rng *rand.Rand) int {
+	rewritten := make([]bool, w*h)
+	arcs := 0
+	for _, src := range []int{SrcTransitPath, SrcBRoutePath} {
+		arcs += gen.rewriteStraightRuns(w, h, src, rng, rewritten)
+	}
+	return arcs
+}
+
+// rewriteStraightRuns は Source が src の航路について直線を円弧に置き換える
+func (gen *World2Generator) rewriteStraightRuns(w, h, src int, rng *rand.Rand, rewritten []bool) int {
+	isRun := func(x, y int) bool {
+		if x < 0 || x >= w || y < 0 || y >= h {
+			return false
+		}
+		t := gen.World2.Tiles[x][y]
+		return t.Type == W2TileVariableOcean && t.Source == src
+	}
+
+	arcs := 0
+	for _, d := range [][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}} {
+		for x := 0; x < w; x++ {
+			for y := 0; y < h; y++ {
+				// 直線の始点 (1つ手前が同じ航路でない) だけを見る
+				if !isRun(x, y) || isRun(x-d[0], y-d[1]) {
+					continue
+				}
+				n := 0
+				touched := false
+				for isRun(x+d[0]*n, y+d[1]*n) {
+					if rewritten[(y+d[1]*n)*w+x+d[0]*n] {
+						touched = true
+					}
+					n++
+				}
+				if n < route1ArcMinRun || touched {
+					continue
+				}
+				if gen.drawRouteArc(w, h, x, y, d, n, src, rng, rewritten) {
+					arcs++
+				}
+			}
+		}
+	}
+	return arcs
+}
+
+// drawRouteArc は (x, y) から方向 d に n マス続く直線を、両端を通る円弧に描き直す
+// 左右どちらに膨らませても陸地や他の航路にかかる場合は何もしない
+func (gen *World2Generator) drawRouteArc(w, h, x, y int, d [2]int, n, src int, rng *rand.Rand, rewritten []bool) bool {
+	x1, y1 := x+d[0]*(n-1), y+d[1]*(n-1)
+	fx0, fy0, fx1, fy1 := float64(x), float64(y), float64(x1), float64(y1)
+	chord := math.Hypot(fx1-fx0, fy1-fy0)
+	mx, my := (fx0+fx1)/2, (fy0+fy1)/2
+	nx, ny := -(fy1-fy0)/chord, (fx1-fx0)/chord
+
+	// 弦の 1/4 を膨らみ (矢高) とする円の半径
+	sagitta := chord / 4
+	radius := (chord*chord/4 + sagitta*sagitta) / (2 * sagitta)
+
+	arcTiles := func(side float64) ([][2]int, bool) {
+		var tiles [][2]int
+		ok := true
+		steps := n * 2
+		px, py := x, y
+		for i := 1; i <= steps; i++ {
+			t := float64(i) / float64(steps)
+			bx, by := fx0+(fx1-fx0)*t, fy0+(fy1-fy0)*t
+			off := math.Sqrt(math.Max(0, radius*radius-math.Pow(math.Hypot(bx-mx, by-my), 2))) - (radius - sagitta)
+			cx, cy := int(math.Round(bx+nx*off*side)), int(math.Round(by+ny*off*side))
+			Bresenham4(px, py, cx, cy, func(tx, ty int) {
+				if tx < 3 || tx >= w-3 || ty < 3 || ty >= h-3 {
+					ok = false
+					return
+				}
+				tile := gen.World2.Tiles[tx][ty]
+				if tile.Type != W2TileVariableOcean || (tile.Source != SrcNone && tile.Source != src) {
+					ok = false
+					return
+				}
+				tiles = append(tiles, [2]int{tx, ty})
+			})
+			px, py = cx, cy
+		}
+		return tiles, ok
+	}
+
+	side := 1.0
+	if rng.Intn(2) == 0 {
+		side = -1.0
+	}
+	tiles, ok := arcTiles(side)
+	if !ok {
+		tiles, ok = arcTiles(-side)
+	}
+	if !ok {
+		return false
+	}
+
+	// 元の直線 (両端は残す) を消してから円弧を描く
+	for i := 0; i < n; i++ {
+		tx, ty := x+d[0]*i, y+d[1]*i
+		rewritten[ty*w+tx] = true
+		if i > 0 && i < n-1 {
+			gen.World2.Tiles[tx][ty].Source = SrcNone
+		}
+	}
+	for _, p := range tiles {
+		gen.World2.Tiles[p[0]][p[1]].Source = src
+		rewritten[p[1]*w+p[0]] = true
+	}
+	return true
+}
+
+// measureRoute1 は孤立島 (SrcIsland の土) ごとに接している航路1のタイル数を数え、
+// 島ごとの情報と、重複なしの総タイル数を返す
+func (gen *World2Generator) measureRoute1(w, h int) ([]Route1Info, float64) {
+	type P struct{ x, y int }
+
+	// 航路タイルを8近傍で連結成分に分ける
+	routeComp := make([]int, w*h)
+	for i := range routeComp {
+		routeComp[i] = -1
+	}
+	var routeSize []int
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if routeComp[y*w+x] >= 0 || !isRoute1Tile(gen.World2.Tiles[x][y]) {
+				continue
+			}
+			id := len(routeSize)
+			size := 0
+			routeComp[y*w+x] = id
+			queue := []P{{x, y}}
+			for len(queue) > 0 {
+				p := queue[0]
+				queue = queue[1:]
+				size++
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						tx, ty := p.x+dx, p.y+dy
+						if tx < 0 || tx >= w || ty < 0 || ty >= h || routeComp[ty*w+tx] >= 0 {
+							continue
+						}
+						if isRoute1Tile(gen.World2.Tiles[tx][ty]) {
+							routeComp[ty*w+tx] = id
+							queue = append(queue, P{tx, ty})
+						}
+					}
+				}
+			}
+			routeSize = append(routeSize, size)
+		}
+	}
+
+	// 孤立島を4近傍で連結成分に分け、周囲8マスに接している航路成分を集める
+	isIsland := func(x, y int) bool {
+		t := gen.World2.Tiles[x][y]
+		return t.Type == W2TileSoil && t.Source == SrcIsland
+	}
+	seen := make([]bool, w*h)
+	used := make([]bool, len(routeSize))
+	infos := []Route1Info{}
+	total := 0
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if seen[y*w+x] || !isIsland(x, y) {
+				continue
+			}
+			minX, minY, maxX, maxY := x, y, x, y
+			var touch []int
+			seen[y*w+x] = true
+			queue := []P{{x, y}}
+			for len(queue) > 0 {
+				p := queue[0]
+				queue = queue[1:]
+				minX, maxX = min(minX, p.x), max(maxX, p.x)
+				minY, maxY = min(minY, p.y), max(maxY, p.y)
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						tx, ty := p.x+dx, p.y+dy
+						if tx < 0 || tx >= w || ty < 0 || ty >= h {
+							continue
+						}
+						if id := routeComp[ty*w+tx]; id >= 0 {
+							dup := false
+							for _, t := range touch {
+								dup = dup || t == id
+							}
+							if !dup {
+								touch = append(touch, id)
+							}
+						}
+						if (dx == 0 || dy == 0) && !seen[ty*w+tx] && isIsland(tx, ty) {
+							seen[ty*w+tx] = true
+							queue = append(queue, P{tx, ty})
+						}
+					}
+				}
+			}
+			if len(touch) == 0 {
+				continue // 航路が引かれていない島は孤立島ではない
+			}
+			dist := 0
+			for _, id := range touch {
+				dist += routeSize[id]
+				if !used[id] {
+					used[id] = true
+					total += routeSize[id]
+				}
+			}
+			infos = append(infos, Route1Info{
+				Island: Rect{X: minX, Y: minY, W: maxX - minX + 1, H: maxY - minY + 1},
+				Dist:   float64(dist),
+			})
+		}
+	}
+	return infos, float64(total)
+}
@@ -0,0 +1,42 @@
+// filename: world2/phase_transit_route2_calc.go
+package world2
+
+import (
+	"math/rand"
+)
+
+// 航路2の生成可否判定を行う (結果は Route2Needed に入り、route2_draw の Skip 条件になる)
+func (gen *World2Generator) PhaseTransitRoute2Calc(w, h int, rng *rand.Rand) {
+	gen.PhaseName = "Transit Route 2 (Secondary Calc)"
+	gen.Route2Needed = false
+
+	// 1. 航路1の長さ判定 (25マス以上)
+	if gen.TotalRoute1Dist < 25.0 {
+		gen.PhaseName += " (Skipped: Route1 too short)"
+		return
+	}
+
+	// 2. 島の数を数える (航路1でつながった孤立島の数。島は複数マスなのでタイル数ではなく島ごとに数える)
+	islandCount := len(gen.Route1)
+
+	// 3. 確率判定
+	isSecondaryRouteNeeded := false
+	r := rng.Intn(100)
+
+	switch islandCount {
+	case 1:
+		if r < 30 { isSecondaryRouteNeeded = true } // 30%
+	case 2:
+		if r < 70 { isSecondaryRouteNeeded = true } // 70%
+	case 3:
+		if r < 10 { isSecondaryRouteNeeded = true } // 10%
+	case 4:
+		if r < 5 { isSecondaryRouteNeeded = true }  // 5%
+	}
+
+	gen.Route2Needed = isSecondaryRouteNeeded
+	if isSecondaryRouteNeeded {
+		// 描画フェーズに進む
+		gen.PhaseName += " -> DRAW"
+	}
+}
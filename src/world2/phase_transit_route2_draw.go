@@ -0,0 +1,134 @@
+// filename: world2/phase_transit_route2_draw.go
+package world2
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// route2Pad は孤立島の外接矩形から円形航路までの余白 (マス)
+// 輪が陸地にかかる時は route2MaxPad まで1マスずつ広げ、それでも収まらなければ (外周に近い島) 1マスまで狭める
+const (
+	route2Pad    = 3
+	route2MaxPad = 12
+)
+
+// route2Loop は孤立島 r を囲む楕円の輪を、角度 start から一周する4近傍のマスの列で返す (ax, ay は楕円の半径)
+// 輪のマスがすべて何もない海か A/B航路 (陸地・浅瀬・外周の固定海にかからない) になる余白を
+// route2Pad, route2Pad+1, ..., route2MaxPad, route2Pad-1, ..., 1 の順に探し、どれも収まらなければ nil を返す
+func (gen *World2Generator) route2Loop(r Route1Info, w, h int, start float64) (ring []Point, ax, ay float64) {
+	// 孤立島の外接矩形の四隅を通る楕円 (半径 x√2) に余白を足す
+	cx := float64(r.Island.X) + float64(r.Island.W-1)/2
+	cy := float64(r.Island.Y) + float64(r.Island.H-1)/2
+	var pads []int
+	for pad := route2Pad; pad <= route2MaxPad; pad++ {
+		pads = append(pads, pad)
+	}
+	for pad := route2Pad - 1; pad >= 1; pad-- {
+		pads = append(pads, pad)
+	}
+	for _, pad := range pads {
+		ax = float64(r.Island.W)/2*math.Sqrt2 + float64(pad)
+		ay = float64(r.Island.H)/2*math.Sqrt2 + float64(pad)
+		// 楕円の長い方の半径で周長を見積もり、1マスに2点以上を打つ
+		steps := int(math.Ceil(4 * math.Pi * math.Max(ax, ay)))
+		ring = ring[:0]
+		fits := true
+		add := func(x, y int) {
+			if x < 3 || x >= w-3 || y < 3 || y >= h-3 || gen.World2.Tiles[x][y].Type != W2TileVariableOcean {
+				fits = false
+				return
+			}
+			ring = append(ring, Point{x, y})
+		}
+		px, py := int(math.Round(cx+ax*math.Cos(start))), int(math.Round(cy+ay*math.Sin(start)))
+		for i := 1; i <= steps && fits; i++ {
+			a := start + 2*math.Pi*float64(i)/float64(steps)
+			nx, ny := int(math.Round(cx+ax*math.Cos(a))), int(math.Round(cy+ay*math.Sin(a)))
+			Bresenham4(px, py, nx, ny, add)
+			px, py = nx, ny
+		}
+		if fits {
+			return ring, ax, ay
+		}
+	}
+	return nil, 0, 0
+}
+
+// 航路2を描画する
+func (gen *World2Generator) PhaseTransitRoute2Draw(w, h int, rng *rand.Rand) {
+	if len(gen.Route1) == 0 {
+		gen.PhaseName = "Transit Route 2 (Skipped: no isolated islands)"
+		return
+	}
+
+	// 1. 大陸の重心 (孤立島・経由島以外の陸地の平均位置)
+	var sumX, sumY, n float64
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			t := gen.World2.Tiles[x][y]
+			if (t.Type == W2TileSoil || t.Type == W2TileCliff) && t.Source != SrcIsland {
+				sumX += float64(x)
+				sumY += float64(y)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		gen.PhaseName = "Transit Route 2 (Skipped: no continent)"
+		return
+	}
+	centerX, centerY := sumX/n, sumY/n
+
+	// 陸地と既存の A/B航路は避け、何もない海だけを航路2にする
+	tiles := 0
+	mark := func(x, y int) {
+		if x < 3 || x >= w-3 || y < 3 || y >= h-3 {
+			return
+		}
+		t := &gen.World2.Tiles[x][y]
+		if t.Type == W2TileVariableOcean && t.Source == SrcNone {
+			t.Source = SrcRoute2Path
+			tiles++
+		}
+	}
+	isLand := func(x, y int) bool {
+		t := gen.World2.Tiles[x][y].Type
+		return t == W2TileSoil || t == W2TileCliff || t == W2TileTransit
+	}
+
+	loops := 0
+	for i, r := range gen.Route1 {
+		// 2. 孤立島を囲む輪を一周させる (出発角は乱数で決める)
+		start := rng.Float64() * 2 * math.Pi
+		ring, ax, ay := gen.route2Loop(r, w, h, start)
+		if ring == nil {
+			continue
+		}
+		gen.Route1[i].Loop = true
+		loops++
+		for _, p := range ring {
+			mark(p.X, p.Y)
+		}
+		cx := float64(r.Island.X) + float64(r.Island.W-1)/2
+		cy := float64(r.Island.Y) + float64(r.Island.H-1)/2
+
+		// 3. 大陸の重心に最も近い楕円上の点から、大陸の海岸まで航路を延ばす
+		a := math.Atan2((centerY-cy)/ay, (centerX-cx)/ax)
+		lx, ly := int(math.Round(cx+ax*math.Cos(a))), int(math.Round(cy+ay*math.Sin(a)))
+		reached := false
+		Bresenham4(lx, ly, int(math.Round(centerX)), int(math.Round(centerY)), func(x, y int) {
+			if reached || x < 0 || x >= w || y < 0 || y >= h {
+				return
+			}
+			if isLand(x, y) {
+				reached = true
+				return
+			}
+			mark(x, y)
+		})
+	}
+
+	gen.PhaseName = fmt.Sprintf("Transit Route 2 (%d/%d loops, %d tiles)", loops, len(gen.Route1), tiles)
+}
@@ -1,13 +1,13 @@
 // filename: world2/phase_transit_start.go
-package main
+package world2
 
 import (
 	"math"
 	"math/rand"
 )
 
-func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator) {
-	gen.PhaseName = "8. Transit Islands (Route 1)"
+func (gen *World2Generator) PhaseTransitStart(w, h int, rng *rand.Rand) {
+	gen.PhaseName = "Transit Islands (Route 1)"
 	
 	// *** 修正: markPath ローカル関数をここに定義 ***
 	markPath := func(x1, y1, x2, y2 int, w, h int) {
@@ -18,8 +18,8 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 		for i := 0; i <= steps; i++ {
 			tx := int(float64(x1) + float64(dx)*float64(i)/float64(steps))
 			ty := int(float64(y1) + float64(dy)*float64(i)/float64(steps))
-			if tx >= 0 && tx < w && ty >= 0 && ty < h && g.World2.Tiles[tx][ty].Type == W2TileVariableOcean {
-				g.World2.Tiles[tx][ty].Source = SrcTransitPath
+			if tx >= 0 && tx < w && ty >= 0 && ty < h && gen.World2.Tiles[tx][ty].Type == W2TileVariableOcean {
+				gen.World2.Tiles[tx][ty].Source = SrcTransitPath
 			}
 		}
 	}
@@ -29,7 +29,7 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 	islands := []Point{}
 	for x := 0; x < w; x += 5 {
 		for y := 0; y < h; y += 5 {
-			if g.World2.Tiles[x][y].Source == SrcIsland {
+			if gen.World2.Tiles[x][y].Source == SrcIsland {
 				islands = append(islands, Point{x, y})
 			}
 		}
@@ -55,7 +55,7 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 		// 5マス刻みでスキャン（高速化）
 		for x := startX; x <= endX; x += 5 {
 			for y := startY; y <= endY; y += 5 {
-				t := g.World2.Tiles[x][y]
+				t := gen.World2.Tiles[x][y]
 				if (t.Type == W2TileSoil || t.Type == W2TileTransit || t.Type == W2TileCliff) && t.Source != SrcIsland {
 					d := calcDist(tx, ty, x, y)
 					if d < minDist {
@@ -79,7 +79,7 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 				tx, ty := x+dx, y+dy
 				if tx >= 0 && tx < w && ty >= 0 && ty < h {
 					totalChecked++
-					t := g.World2.Tiles[tx][ty]
+					t := gen.World2.Tiles[tx][ty]
 					if (t.Type == W2TileSoil || t.Type == W2TileCliff) && t.Source != SrcIsland {
 						landCount++
 					}
@@ -106,13 +106,13 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 			tx := int(float64(x1) + float64(dx)*float64(i)/float64(steps))
 			ty := int(float64(y1) + float64(dy)*float64(i)/float64(steps))
 			if tx >= 0 && tx < w && ty >= 0 && ty < h {
-				tile := g.World2.Tiles[tx][ty]
+				tile := gen.World2.Tiles[tx][ty]
 				// 陸地に当たったら描画を停止
 				if tile.Type == W2TileSoil || tile.Type == W2TileCliff {
 					break
 				}
 				if tile.Type == W2TileVariableOcean {
-					g.World2.Tiles[tx][ty].Source = sourceType
+					gen.World2.Tiles[tx][ty].Source = sourceType
 				}
 			}
 		}
@@ -150,8 +150,8 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 
 			ix, iy := int(px), int(py)
 			if ix >= 0 && ix < w && iy >= 0 && iy < h {
-				if g.World2.Tiles[ix][iy].Type == W2TileVariableOcean {
-					g.World2.Tiles[ix][iy].Source = SrcTransitPath
+				if gen.World2.Tiles[ix][iy].Type == W2TileVariableOcean {
+					gen.World2.Tiles[ix][iy].Source = SrcTransitPath
 				}
 			}
 		}
@@ -180,7 +180,7 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 		// 制御点が陸地上にあるかチェック
 		ctrlIX, ctrlIY := int(ctrlX), int(ctrlY)
 		if ctrlIX >= 0 && ctrlIX < w && ctrlIY >= 0 && ctrlIY < h {
-			ctrlTile := g.World2.Tiles[ctrlIX][ctrlIY]
+			ctrlTile := gen.World2.Tiles[ctrlIX][ctrlIY]
 			if ctrlTile.Type == W2TileSoil || ctrlTile.Type == W2TileCliff {
 				// 制御点が陸地の場合、直線にフォールバック
 				markPathWithColor(x1, y1, x2, y2, w, h, SrcBRoutePath)
@@ -197,13 +197,13 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 
 			ix, iy := int(px), int(py)
 			if ix >= 0 && ix < w && iy >= 0 && iy < h {
-				tile := g.World2.Tiles[ix][iy]
+				tile := gen.World2.Tiles[ix][iy]
 				// 陸地に当たったら描画を停止
 				if tile.Type == W2TileSoil || tile.Type == W2TileCliff {
 					break
 				}
 				if tile.Type == W2TileVariableOcean {
-					g.World2.Tiles[ix][iy].Source = SrcBRoutePath
+					gen.World2.Tiles[ix][iy].Source = SrcBRoutePath
 				}
 			}
 		}
@@ -236,7 +236,7 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 			// ジグザグ点が陸地上にあるかチェック
 			nextIX, nextIY := int(nextX), int(nextY)
 			if nextIX >= 0 && nextIX < w && nextIY >= 0 && nextIY < h {
-				nextTile := g.World2.Tiles[nextIX][nextIY]
+				nextTile := gen.World2.Tiles[nextIX][nextIY]
 				if nextTile.Type == W2TileSoil || nextTile.Type == W2TileCliff {
 					// 陸地の場合、オフセットを減らす（直線に近づける）
 					offset = offset * 0.3
@@ -251,11 +251,11 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 			// 小さい経由島を配置（1x1、緑）
 			ix, iy := int(nextX), int(nextY)
 			if ix >= 3 && ix < w-3 && iy >= 3 && iy < h-3 {
-				tile := g.World2.Tiles[ix][iy]
+				tile := gen.World2.Tiles[ix][iy]
 				// 陸地でない場合のみ島を配置
 				if tile.Type == W2TileVariableOcean {
-					g.World2.Tiles[ix][iy].Type = W2TileTransit
-					g.World2.Tiles[ix][iy].Source = SrcBRouteIsland
+					gen.World2.Tiles[ix][iy].Type = W2TileTransit
+					gen.World2.Tiles[ix][iy].Source = SrcBRouteIsland
 					gen.NewSoils[iy*w+ix] = true
 				}
 			}
@@ -272,17 +272,29 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 		halfBound := 1 // 3x3 の中心から1マス
 		count := 0
 		targetCount := 4 + rng.Intn(4) // 4〜7タイル
+		// 3x3 に残っている海がそれより少なければ、その数で打ち切る (歩き続けても増やせないため)
+		free := 0
+		for tx := cx - halfBound; tx <= cx+halfBound; tx++ {
+			for ty := cy - halfBound; ty <= cy+halfBound; ty++ {
+				if tx >= 0 && tx < w && ty >= 0 && ty < h && gen.World2.Tiles[tx][ty].Type == W2TileVariableOcean {
+					free++
+				}
+			}
+		}
+		if free < targetCount {
+			targetCount = free
+		}
 
 		// 3x3の島を作成
 		wx, wy := cx, cy
 		safety := 0
-		for count < targetCount && safety < 100 {
+		for count < targetCount && safety < 1000 { // 3x3 の残り全部を埋める場合もあるので、巡り切るのに十分な歩数
 			safety++
 			if wx >= cx-halfBound && wx <= cx+halfBound && wy >= cy-halfBound && wy <= cy+halfBound {
 				tx, ty := wx, wy
-				if tx >= 0 && tx < w && ty >= 0 && ty < h && g.World2.Tiles[tx][ty].Type == W2TileVariableOcean {
-					g.World2.Tiles[tx][ty].Type = W2TileTransit
-					g.World2.Tiles[tx][ty].Source = SrcBridge
+				if tx >= 0 && tx < w && ty >= 0 && ty < h && gen.World2.Tiles[tx][ty].Type == W2TileVariableOcean {
+					gen.World2.Tiles[tx][ty].Type = W2TileTransit
+					gen.World2.Tiles[tx][ty].Source = SrcBridge
 					gen.NewSoils[ty*w+tx] = true
 					count++
 				}
@@ -301,6 +313,9 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 			if wy < cy-halfBound { wy = cy - halfBound }
 			if wy > cy+halfBound { wy = cy + halfBound }
 		}
+		if count < targetCount {
+			gen.noteSafetyCap("transit island walk")
+		}
 		
 		// 浅瀬を3か所生成
 		shallowCount := 0
@@ -309,14 +324,14 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 			tx, ty := cx+dx, cy+dy
 			
 			// 経由島の外側 (海) で、かつ固定海でないこと
-			if tx >= 3 && tx < w-3 && ty >= 3 && ty < h-3 && g.World2.Tiles[tx][ty].Type == W2TileVariableOcean {
+			if tx >= 3 && tx < w-3 && ty >= 3 && ty < h-3 && gen.World2.Tiles[tx][ty].Type == W2TileVariableOcean {
 				// 周囲1マスにTransitタイルがあるかチェック
 				hasTransitNeighbor := false
 				for ndy := -1; ndy <= 1; ndy++ {
 					for ndx := -1; ndx <= 1; ndx++ {
 						// 境界チェックを追加
 						if tx+ndx >= 0 && tx+ndx < w && ty+ndy >= 0 && ty+ndy < h {
-							if g.World2.Tiles[tx+ndx][ty+ndy].Type == W2TileTransit {
+							if gen.World2.Tiles[tx+ndx][ty+ndy].Type == W2TileTransit {
 								hasTransitNeighbor = true
 								break
 							}
@@ -326,7 +341,7 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 				}
 				
 				if hasTransitNeighbor {
-					g.World2.Tiles[tx][ty].Type = W2TileShallow
+					gen.World2.Tiles[tx][ty].Type = W2TileShallow
 					gen.NewSoils[ty*w+tx] = true
 					shallowCount++
 				}
@@ -386,7 +401,7 @@ func (g *Game) PhaseTransitStart(w, h int, rng *rand.Rand, gen *World2Generator)
 					for dx := -checkRadius; dx <= checkRadius; dx++ {
 						tx, ty := ix+dx, iy+dy
 						if tx >= 0 && tx < w && ty >= 0 && ty < h {
-							t := g.World2.Tiles[tx][ty]
+							t := gen.World2.Tiles[tx][ty]
 							// 孤立島以外の土地（大陸）が近くにある
 							if (t.Type == W2TileSoil || t.Type == W2TileCliff) && t.Source != SrcIsland {
 								tooCloseToLand = true
@@ -0,0 +1,141 @@
+// filename: world2/phases.go
+package world2
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Phase は生成パイプラインの1段
+type Phase struct {
+	Key     string // 設定 (Phases / DisablePhases) で段を指定するための名前
+	ID      int    // Phase_* 定数。CurrentStep と PhaseName の番号に使う
+	Name    string // 既定の PhaseName (Run が上書きしなかった場合に使う)
+	Enabled bool
+	// Requires はこの段より前に有効な状態で並んでいなければならない段の Key (この段が使う結果を作る段)
+	Requires []string
+	// Skip は直前までの生成結果から、この段を飛ばすかを判定する (nil なら飛ばさない)
+	Skip func(gen *World2Generator) bool
+	Run  func(gen *World2Generator, w, h int, rng *rand.Rand)
+}
+
+// active は段が有効で、かつ Skip 条件に当たらないかを返す
+func (p Phase) active(gen *World2Generator) bool {
+	return p.Enabled && (p.Skip == nil || !p.Skip(gen))
+}
+
+// phaseRegistry は Key から段 (土の配置のように複数段のまとまりもある) を引く表
+var phaseRegistry = map[string][]Phase{}
+
+// defaultPhaseOrder は Phases 未指定時の実行順
+var defaultPhaseOrder []string
+
+// RegisterPhase は key で参照できる段を登録する (同じ key は上書き)
+// 登録しただけでは実行されず、GenConfig.Phases の並びに key を入れると組み込まれる
+func RegisterPhase(key string, phases ...Phase) {
+	for i := range phases {
+		phases[i].Key = key
+		phases[i].Enabled = true
+	}
+	phaseRegistry[key] = phases
+}
+
+// DefaultPhaseOrder は標準の段の並び (Key) を返す
+func DefaultPhaseOrder() []string {
+	return append([]string(nil), defaultPhaseOrder...)
+}
+
+// BuildPhases は GenConfig の Phases (並び順) と DisablePhases (無効化) から段の列を作る
+func BuildPhases(cfg GenConfig) ([]Phase, error) {
+	order := cfg.Phases
+	if len(order) == 0 {
+		order = defaultPhaseOrder
+	}
+	var phases []Phase
+	for _, key := range order {
+		group, ok := phaseRegistry[key]
+		if !ok {
+			return nil, fmt.Errorf("Unknown phase: %s", key)
+		}
+		phases = append(phases, group...)
+	}
+	for _, key := range cfg.DisablePhases {
+		if _, ok := phaseRegistry[key]; !ok {
+			return nil, fmt.Errorf("Unknown phase: %s", key)
+		}
+		for i := range phases {
+			if phases[i].Key == key {
+				phases[i].Enabled = false
+			}
+		}
+	}
+	if err := checkRequires(phases); err != nil {
+		return nil, err
+	}
+	return phases, nil
+}
+
+// checkRequires は有効な段ごとに、Requires の段が無効にされていないか・後ろに並んでいないかを調べる
+func checkRequires(phases []Phase) error {
+	ran := map[string]bool{} // ここまでに並んだ有効な段
+	for _, p := range phases {
+		if !p.Enabled {
+			continue
+		}
+		for _, key := range p.Requires {
+			if ran[key] {
+				continue
+			}
+			for _, q := range phases {
+				if q.Key == key && q.Enabled {
+					return fmt.Errorf("Phase %s must come after %s", p.Key, key)
+				}
+			}
+			return fmt.Errorf("Phase %s requires %s", p.Key, key)
+		}
+		ran[p.Key] = true
+	}
+	return nil
+}
+
+func init() {
+	RegisterPhase("init", Phase{ID: Phase_Init, Name: "Init", Run: (*World2Generator).PhaseInit})
+	RegisterPhase("mask", Phase{ID: Phase_MaskGen, Name: "Mask", Run: (*World2Generator).PhaseMaskGen})
+
+	// 土の配置は 10% ずつ 10 段に分ける (段の ID から進捗を計算する)
+	var soil []Phase
+	for id := Phase_SoilStart; id <= Phase_SoilProgressEnd; id++ {
+		soil = append(soil, Phase{ID: id, Name: "Soil Progress", Requires: []string{"mask"}, Run: (*World2Generator).PhaseSoilProgress})
+	}
+	RegisterPhase("soil", soil...)
+
+	RegisterPhase("bridge", Phase{ID: Phase_Bridge, Name: "Bridge", Run: (*World2Generator).PhaseBridge})
+	RegisterPhase("centering", Phase{ID: Phase_Centering, Name: "Safe Centering", Run: (*World2Generator).PhaseCentering})
+	RegisterPhase("islands_quad", Phase{ID: Phase_IslandsQuad, Name: "Islands (Quad)", Run: (*World2Generator).PhaseIslandsQuad})
+	RegisterPhase("islands_rand", Phase{ID: Phase_IslandsRand, Name: "Islands (Random)", Run: (*World2Generator).PhaseIslandsRand})
+	RegisterPhase("transit_start", Phase{ID: Phase_Transit_Start, Name: "Transit Islands (Route 1)", Run: (*World2Generator).PhaseTransitStart})
+	RegisterPhase("island_shallow", Phase{ID: Phase_IslandShallowAdjust, Name: "Island Shallow Adjust", Run: (*World2Generator).PhaseIslandShallowAdjust})
+	RegisterPhase("route1", Phase{ID: Phase_Transit_Route1, Name: "Transit Route 1", Run: (*World2Generator).PhaseTransitRoute1})
+	RegisterPhase("route2_calc", Phase{ID: Phase_Transit_Route2_Calc, Name: "Transit Route 2 (Calc)", Requires: []string{"route1"}, Run: (*World2Generator).PhaseTransitRoute2Calc})
+	RegisterPhase("route2_draw", Phase{
+		ID:       Phase_Transit_Route2_Draw,
+		Name:     "Transit Route 2",
+		Requires: []string{"route2_calc"},
+		// 航路2の要否は route2_calc の確率判定で決まる
+		Skip: func(gen *World2Generator) bool { return !gen.Route2Needed },
+		Run:  (*World2Generator).PhaseTransitRoute2Draw,
+	})
+	RegisterPhase("cliffs", Phase{ID: Phase_CliffsShallows, Name: "Cliffs & Shallows", Run: (*World2Generator).PhaseCliffsShallows})
+	RegisterPhase("lakes", Phase{ID: Phase_LakesFinal, Name: "Lakes", Run: (*World2Generator).PhaseLakesFinal})
+	RegisterPhase("elevation", Phase{ID: Phase_Elevation, Name: "Elevation", Run: (*World2Generator).PhaseElevation})
+	RegisterPhase("rivers", Phase{ID: Phase_Rivers, Name: "Rivers", Requires: []string{"elevation"}, Run: (*World2Generator).PhaseRivers})
+	RegisterPhase("climate", Phase{ID: Phase_Climate, Name: "Climate & Biomes", Requires: []string{"elevation"}, Run: (*World2Generator).PhaseClimate})
+	RegisterPhase("nations", Phase{ID: Phase_Nations, Name: "Nations", Run: (*World2Generator).PhaseNations})
+	RegisterPhase("settlements", Phase{ID: Phase_Settlements, Name: "Settlements", Run: (*World2Generator).PhaseSettlements})
+
+	defaultPhaseOrder = []string{
+		"init", "mask", "soil", "bridge", "centering", "islands_quad", "islands_rand",
+		"transit_start", "island_shallow", "route1", "route2_calc", "route2_draw",
+		"cliffs", "lakes", "elevation", "rivers", "climate", "nations", "settlements",
+	}
+}
@@ -0,0 +1,172 @@
+// filename: world2/phases_test.go
+package world2
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// executedIDs は History の PhaseName の番号 (実行した段の ID) を順に返す
+func executedIDs(t *testing.T, gen *World2Generator) []int {
+	t.Helper()
+	var ids []int
+	for _, s := range gen.History[1:] {
+		var id int
+		if _, err := fmt.Sscanf(s.PhaseName, "%d.", &id); err != nil {
+			t.Fatalf("PhaseName %q has no step number", s.PhaseName)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// executedName は段 id を実行した時のスナップショットの PhaseName を番号なしで返す (実行していなければ "")
+func executedName(gen *World2Generator, id int) string {
+	prefix := fmt.Sprintf("%d. ", id)
+	for _, s := range gen.History {
+		if strings.HasPrefix(s.PhaseName, prefix) {
+			return strings.TrimPrefix(s.PhaseName, prefix)
+		}
+	}
+	return ""
+}
+
+// withTestPhase はテストの間だけ key の段を登録する
+// 登録表を丸ごと複製してから登録し、テストの終わりに元の表へ戻す
+func withTestPhase(t *testing.T, key string, phases ...Phase) {
+	t.Helper()
+	saved := phaseRegistry
+	phaseRegistry = make(map[string][]Phase, len(saved)+1)
+	for k, v := range saved {
+		phaseRegistry[k] = v
+	}
+	t.Cleanup(func() { phaseRegistry = saved })
+	RegisterPhase(key, phases...)
+}
+
+// 標準の並びでは段の ID 順に実行され、崖と浅瀬・湖まで進んで完了すること
+func TestDefaultPhasesRunInIDOrder(t *testing.T) {
+	gen := newTestGenerator(t, DefaultConfig(), 2)
+	gen.Run()
+	if !gen.IsFinished || gen.PhaseIndex != len(gen.Phases) {
+		t.Fatalf("not finished: index %d of %d", gen.PhaseIndex, len(gen.Phases))
+	}
+	ids := executedIDs(t, gen)
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("phase %d ran after %d: %v", ids[i], ids[i-1], ids)
+		}
+	}
+	seen := map[int]bool{}
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, id := range []int{Phase_Init, Phase_MaskGen, Phase_SoilStart, Phase_SoilProgressEnd, Phase_CliffsShallows, Phase_LakesFinal} {
+		if !seen[id] {
+			t.Errorf("phase %d was not executed: %v", id, ids)
+		}
+	}
+	if seen[Phase_Transit_Route2_Draw] != gen.Route2Needed {
+		t.Errorf("route2_draw executed=%v but Route2Needed=%v", seen[Phase_Transit_Route2_Draw], gen.Route2Needed)
+	}
+}
+
+// DisablePhases で指定した段は実行されず、Phases の並びで順番を変えられること
+func TestPhasesFromConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DisablePhases = []string{"islands_rand", "cliffs"}
+	gen := newTestGenerator(t, cfg, 2)
+	gen.Run()
+	for _, id := range executedIDs(t, gen) {
+		if id == Phase_IslandsRand || id == Phase_CliffsShallows {
+			t.Errorf("disabled phase %d was executed", id)
+		}
+	}
+
+	cfg = DefaultConfig()
+	cfg.Phases = []string{"init", "mask", "soil", "islands_quad", "centering", "lakes"}
+	gen = newTestGenerator(t, cfg, 2)
+	gen.Run()
+	ids := executedIDs(t, gen)
+	want := []int{Phase_Init, Phase_MaskGen}
+	for id := Phase_SoilStart; id <= Phase_SoilProgressEnd; id++ {
+		want = append(want, id)
+	}
+	want = append(want, Phase_IslandsQuad, Phase_Centering, Phase_LakesFinal)
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("executed %v, want %v", ids, want)
+	}
+}
+
+func TestUnknownPhaseIsRejected(t *testing.T) {
+	for _, cfg := range []GenConfig{
+		func() GenConfig { c := DefaultConfig(); c.Phases = []string{"init", "volcanoes"}; return c }(),
+		func() GenConfig { c := DefaultConfig(); c.DisablePhases = []string{"volcanoes"}; return c }(),
+	} {
+		if _, err := NewGenerator(cfg, 1); err == nil || !strings.Contains(err.Error(), "volcanoes") {
+			t.Errorf("NewGenerator error = %v, want unknown phase", err)
+		}
+	}
+}
+
+// RegisterPhase で登録した段を Phases に入れると組み込まれ、Undo で戻せること
+func TestRegisteredPhaseCanBeInserted(t *testing.T) {
+	const id = 90
+	withTestPhase(t, "test_marker", Phase{ID: id, Name: "Marker", Run: func(gen *World2Generator, w, h int, rng *rand.Rand) {
+		gen.World2.Tiles[w/2][h/2] = World2Tile{Type: W2TileTransit}
+	}})
+	cfg := DefaultConfig()
+	cfg.Phases = append(DefaultPhaseOrder(), "test_marker")
+	gen := newTestGenerator(t, cfg, 2)
+	gen.Run()
+
+	last := gen.History[len(gen.History)-1]
+	if last.PhaseName != fmt.Sprintf("%d. Marker", id) {
+		t.Errorf("last phase = %q", last.PhaseName)
+	}
+	if gen.World2.Tiles[cfg.W/2][cfg.H/2].Type != W2TileTransit {
+		t.Errorf("registered phase did not run")
+	}
+
+	gen.UndoStep()
+	if gen.IsFinished || gen.CurrentStep != id || gen.Phases[gen.PhaseIndex].Key != "test_marker" {
+		t.Errorf("after undo: finished=%v step=%d", gen.IsFinished, gen.CurrentStep)
+	}
+	gen.Run()
+	if !gen.IsFinished {
+		t.Errorf("replay after undo did not finish")
+	}
+}
+
+// 必要な段を無効にしたり後ろに並べたりした設定は、生成を始める前にエラーになること
+func TestPhaseRequiresAreChecked(t *testing.T) {
+	disabled := DefaultConfig()
+	disabled.MainType = 3
+	disabled.DisablePhases = []string{"mask"}
+	reordered := DefaultConfig()
+	reordered.MainType = 3
+	reordered.Phases = []string{"init", "soil", "mask"}
+	for _, c := range []struct {
+		cfg  GenConfig
+		want string
+	}{
+		{disabled, "Phase soil requires mask"},
+		{reordered, "Phase soil must come after mask"},
+	} {
+		if _, err := NewGenerator(c.cfg, 1); err == nil || err.Error() != c.want {
+			t.Errorf("NewGenerator error = %v, want %q", err, c.want)
+		}
+		if err := c.cfg.Validate(); err == nil {
+			t.Errorf("Validate accepted %v / disabled %v", c.cfg.Phases, c.cfg.DisablePhases)
+		}
+	}
+
+	// 必要な段ごと無効にすれば通る
+	cfg := DefaultConfig()
+	cfg.DisablePhases = []string{"route1", "route2_calc", "route2_draw"}
+	if _, err := NewGenerator(cfg, 1); err != nil {
+		t.Errorf("disabling a phase with its dependents: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+// filename: world2/river_test.go
+package world2
+
+import (
+	"fmt"
+	"testing"
+)
+
+// riverComponents は4方向につながった川のタイルのまとまりごとに、タイル数と水域に接しているかを返す
+func riverComponents(m *WorldMap2) (sizes []int, reachSink []bool) {
+	seen := make([][]bool, m.Width)
+	for x := range seen {
+		seen[x] = make([]bool, m.Height)
+	}
+	dxs := []int{0, 1, 0, -1}
+	dys := []int{-1, 0, 1, 0}
+	for x := 0; x < m.Width; x++ {
+		for y := 0; y < m.Height; y++ {
+			if !m.Tiles[x][y].IsRiver || seen[x][y] {
+				continue
+			}
+			size, sink := 0, false
+			stack := [][2]int{{x, y}}
+			seen[x][y] = true
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				size++
+				for i := 0; i < 4; i++ {
+					nx, ny := p[0]+dxs[i], p[1]+dys[i]
+					if nx < 0 || nx >= m.Width || ny < 0 || ny >= m.Height {
+						continue
+					}
+					if isRiverSink(m.Tiles[nx][ny]) {
+						sink = true
+					}
+					if m.Tiles[nx][ny].IsRiver && !seen[nx][ny] {
+						seen[nx][ny] = true
+						stack = append(stack, [2]int{nx, ny})
+					}
+				}
+			}
+			sizes = append(sizes, size)
+			reachSink = append(reachSink, sink)
+		}
+	}
+	return sizes, reachSink
+}
+
+// 川は土の上だけを通り、どの水系も海か湖に注ぎ、本数と最小の長さの設定を守ること
+func TestRiversFlowIntoWater(t *testing.T) {
+	cfg := DefaultConfig()
+	for _, seed := range []int64{1, 2, 3, 4} {
+		gen := newTestGenerator(t, cfg, seed)
+		gen.Run()
+		m := gen.World2
+
+		var rivers int
+		if _, err := fmt.Sscanf(executedName(gen, Phase_Rivers), "Rivers (%d)", &rivers); err != nil {
+			t.Fatalf("seed %d: unexpected phase name %q", seed, executedName(gen, Phase_Rivers))
+		}
+		if rivers == 0 || rivers > cfg.RiverCount {
+			t.Errorf("seed %d: %d rivers, want 1..%d", seed, rivers, cfg.RiverCount)
+		}
+		for x := 0; x < m.Width; x++ {
+			for y := 0; y < m.Height; y++ {
+				if m.Tiles[x][y].IsRiver && m.Tiles[x][y].Type != W2TileSoil {
+					t.Fatalf("seed %d: river on tile type %d at (%d,%d)", seed, m.Tiles[x][y].Type, x, y)
+				}
+			}
+		}
+		sizes, sink := riverComponents(m)
+		if len(sizes) == 0 || len(sizes) > rivers {
+			t.Errorf("seed %d: %d river systems for %d rivers", seed, len(sizes), rivers)
+		}
+		for i := range sizes {
+			if sizes[i] < cfg.RiverMinLen {
+				t.Errorf("seed %d: river system of %d tiles is shorter than %d", seed, sizes[i], cfg.RiverMinLen)
+			}
+			if !sink[i] {
+				t.Errorf("seed %d: river system %d does not reach the sea or a lake", seed, i)
+			}
+		}
+	}
+}
+
+func TestRiversDisabled(t *testing.T) {
+	for _, tc := range []struct {
+		count, minLen int
+		want          string
+	}{
+		{0, 8, "Rivers (Skipped)"},
+		{6, 10000, "Rivers (0)"},
+	} {
+		cfg := DefaultConfig()
+		cfg.RiverCount, cfg.RiverMinLen = tc.count, tc.minLen
+		gen := newTestGenerator(t, cfg, 1)
+		gen.Run()
+		if got := executedName(gen, Phase_Rivers); got != tc.want {
+			t.Errorf("count %d minLen %d: phase %q, want %q", tc.count, tc.minLen, got, tc.want)
+		}
+		if sizes, _ := riverComponents(gen.World2); len(sizes) != 0 {
+			t.Errorf("count %d minLen %d: %d river systems drawn", tc.count, tc.minLen, len(sizes))
+		}
+	}
+}
+
+// 窪地があっても流れ先をたどると水域に着き、窪地の縁より高い所へは登らないこと
+func TestDrainageEscapesPits(t *testing.T) {
+	// 左端が海、右へ行くほど高いが x=5 に深い窪地がある 12x3 の土地
+	elev := []int16{0, 10, 20, 30, 40, 5, 50, 60, 70, 80, 90, 100}
+	w, h := len(elev), 3
+	tiles := make([][]World2Tile, w)
+	for x := range tiles {
+		tiles[x] = make([]World2Tile, h)
+		for y := range tiles[x] {
+			tiles[x][y] = World2Tile{Type: W2TileSoil, Elevation: elev[x]}
+			if x == 0 {
+				tiles[x][y] = World2Tile{Type: W2TileVariableOcean}
+			}
+		}
+	}
+	down := drainage(tiles, w, h)
+	for y := 0; y < h; y++ {
+		i := y*w + w - 1
+		for steps := 0; !isRiverSink(tiles[i%w][i/w]); steps++ {
+			if steps > w*h || down[i] < 0 {
+				t.Fatalf("flow from (%d,%d) did not reach the sea", w-1, y)
+			}
+			next := down[i]
+			if tiles[next%w][next/w].Elevation > tiles[i%w][i/w].Elevation && tiles[i%w][i/w].Elevation != 5 {
+				t.Fatalf("flow climbs from %d to %d outside the pit", tiles[i%w][i/w].Elevation, tiles[next%w][next/w].Elevation)
+			}
+			i = next
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// filename: world2/route_test.go
+package world2
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// newRouteTestGenerator は陸地のない w x h のマップを持つ生成器を作る
+func newRouteTestGenerator(w, h int) *World2Generator {
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = w, h
+	return &World2Generator{Config: cfg, World2: NewWorldMap2(w, h), NewSoils: map[int]bool{}}
+}
+
+// 5マス以上まっすぐ続く航路は、両端を保ったまま4近傍でつながった円弧に置き換わること
+func TestStraightRouteBecomesArc(t *testing.T) {
+	gen := newRouteTestGenerator(40, 40)
+	m := gen.World2
+	for x := 10; x < 20; x++ {
+		m.Tiles[x][20].Source = SrcTransitPath
+	}
+
+	if arcs := gen.rewriteStraightRoutes(40, 40, rand.New(rand.NewSource(1))); arcs != 1 {
+		t.Fatalf("rewriteStraightRoutes = %d arcs, want 1", arcs)
+	}
+	if m.Tiles[10][20].Source != SrcTransitPath || m.Tiles[19][20].Source != SrcTransitPath {
+		t.Fatalf("arc endpoints were removed")
+	}
+	if m.Tiles[15][20].Source == SrcTransitPath {
+		t.Errorf("middle of the straight run is still a route")
+	}
+
+	// 始点から航路タイルだけをたどって終点に着けること
+	seen := map[[2]int]bool{{10, 20}: true}
+	stack := [][2]int{{10, 20}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, d := range [][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}} {
+			q := [2]int{p[0] + d[0], p[1] + d[1]}
+			if !seen[q] && m.Tiles[q[0]][q[1]].Source == SrcTransitPath {
+				seen[q] = true
+				stack = append(stack, q)
+			}
+		}
+	}
+	if !seen[[2]int{19, 20}] {
+		t.Errorf("arc does not connect the run endpoints")
+	}
+}
+
+// 航路1の距離は、孤立島に接している航路タイルの実数になること
+func TestMeasureRoute1CountsPathTiles(t *testing.T) {
+	gen := newRouteTestGenerator(40, 40)
+	m := gen.World2
+	for x := 25; x < 28; x++ {
+		for y := 10; y < 12; y++ {
+			m.Tiles[x][y] = World2Tile{Type: W2TileSoil, Source: SrcIsland}
+		}
+	}
+	// 島に接する B航路 6マス + 経由島 1マス + A航路 4マス (斜めでつながる)
+	for x := 19; x < 25; x++ {
+		m.Tiles[x][10].Source = SrcBRoutePath
+	}
+	m.Tiles[18][11] = World2Tile{Type: W2TileTransit, Source: SrcBRouteIsland}
+	for i := 1; i <= 4; i++ {
+		m.Tiles[18-i][11+i].Source = SrcTransitPath
+	}
+	// 島に接していない航路は数えない
+	for x := 5; x < 10; x++ {
+		m.Tiles[x][30].Source = SrcTransitPath
+	}
+	// 航路のない島は孤立島として扱わない
+	m.Tiles[30][30] = World2Tile{Type: W2TileSoil, Source: SrcIsland}
+
+	infos, total := gen.measureRoute1(40, 40)
+	if len(infos) != 1 {
+		t.Fatalf("got %d isolated islands, want 1: %v", len(infos), infos)
+	}
+	if infos[0].Dist != 11 || total != 11 {
+		t.Errorf("Dist = %v, total = %v, want 11", infos[0].Dist, total)
+	}
+	if want := (Rect{X: 25, Y: 10, W: 3, H: 2}); infos[0].Island != want {
+		t.Errorf("Island = %v, want %v", infos[0].Island, want)
+	}
+}
+
+// 生成全体でも TotalRoute1Dist は盤面上の航路タイル数を超えず、孤立島がある時だけ正になること
+func TestRoute1DistWithinPathTiles(t *testing.T) {
+	for _, seed := range []int64{2, 3, 6} {
+		gen := newTestGenerator(t, DefaultConfig(), seed)
+		for gen.CurrentStep <= Phase_Transit_Route1 {
+			gen.NextStep()
+		}
+		tiles := 0
+		for x := range gen.World2.Tiles {
+			for _, tile := range gen.World2.Tiles[x] {
+				if isRoute1Tile(tile) {
+					tiles++
+				}
+			}
+		}
+		if gen.TotalRoute1Dist > float64(tiles) {
+			t.Errorf("seed %d: TotalRoute1Dist %v > %d route tiles", seed, gen.TotalRoute1Dist, tiles)
+		}
+		if (len(gen.Route1) > 0) != (gen.TotalRoute1Dist > 0) {
+			t.Errorf("seed %d: %d islands but TotalRoute1Dist %v", seed, len(gen.Route1), gen.TotalRoute1Dist)
+		}
+	}
+}
+
+// 航路2は孤立島を囲む輪と大陸へ延びる線になり、陸地と既存の航路を上書きしないこと
+func TestRoute2LoopAroundIsland(t *testing.T) {
+	gen := newRouteTestGenerator(60, 60)
+	m := gen.World2
+	for x := 5; x < 16; x++ {
+		for y := 5; y < 55; y++ {
+			m.Tiles[x][y] = World2Tile{Type: W2TileSoil, Source: SrcMain}
+		}
+	}
+	for x := 40; x < 44; x++ {
+		for y := 28; y < 32; y++ {
+			m.Tiles[x][y] = World2Tile{Type: W2TileSoil, Source: SrcIsland}
+		}
+	}
+	for x := 30; x < 40; x++ {
+		m.Tiles[x][29].Source = SrcBRoutePath
+	}
+	gen.Route1 = []Route1Info{{Island: Rect{X: 40, Y: 28, W: 4, H: 4}, Dist: 10}}
+
+	gen.PhaseTransitRoute2Draw(60, 60, rand.New(rand.NewSource(1)))
+
+	var quadrants [4]bool
+	touchesContinent := false
+	for x := 0; x < 60; x++ {
+		if m.Tiles[x][29].Source == SrcRoute2Path && x >= 30 && x < 40 {
+			t.Fatalf("route 2 overwrote the B route at (%d,29)", x)
+		}
+		for y := 0; y < 60; y++ {
+			tile := m.Tiles[x][y]
+			if tile.Source != SrcRoute2Path {
+				continue
+			}
+			if tile.Type != W2TileVariableOcean {
+				t.Fatalf("route 2 on non-ocean tile (%d,%d) type %d", x, y, tile.Type)
+			}
+			if x == 16 {
+				touchesContinent = true
+			}
+			q := 0
+			if x >= 42 {
+				q |= 1
+			}
+			if y >= 30 {
+				q |= 2
+			}
+			quadrants[q] = true
+		}
+	}
+	if quadrants != [4]bool{true, true, true, true} {
+		t.Errorf("route 2 does not surround the island: quadrants %v", quadrants)
+	}
+	if !touchesContinent {
+		t.Errorf("route 2 does not reach the continent coast")
+	}
+}
+
+// enclosedByRoutes は孤立島 r の中心から、航路 (航路2と A/B航路) 以外のマスを4近傍でたどって
+// 外周の固定海に出られないか (島が航路の輪で閉じ込められているか) を返す
+func enclosedByRoutes(m *WorldMap2, r Rect) bool {
+	isRoute := func(t World2Tile) bool {
+		return t.Type == W2TileVariableOcean && (t.Source == SrcRoute2Path || t.Source == SrcTransitPath || t.Source == SrcBRoutePath)
+	}
+	start := Point{r.X + r.W/2, r.Y + r.H/2}
+	seen := map[Point]bool{start: true}
+	queue := []Point{start}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p.X < 3 || p.X >= m.Width-3 || p.Y < 3 || p.Y >= m.Height-3 {
+			return false
+		}
+		for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+			n := Point{p.X + d.X, p.Y + d.Y}
+			if !seen[n] && !isRoute(m.Tiles[n.X][n.Y]) {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return true
+}
+
+// 既定の余白の楕円が大陸にかかる孤立島でも、輪を広げて途切れずに一周させること
+func TestRoute2LoopClearsLand(t *testing.T) {
+	gen := newRouteTestGenerator(60, 60)
+	m := gen.World2
+	for x := 5; x < 34; x++ {
+		for y := 5; y < 55; y++ {
+			m.Tiles[x][y] = World2Tile{Type: W2TileSoil, Source: SrcMain}
+		}
+	}
+	for x := 40; x < 44; x++ {
+		for y := 28; y < 32; y++ {
+			m.Tiles[x][y] = World2Tile{Type: W2TileSoil, Source: SrcIsland}
+		}
+	}
+	for x := 34; x < 40; x++ {
+		m.Tiles[x][29].Source = SrcBRoutePath
+	}
+	island := Rect{X: 40, Y: 28, W: 4, H: 4}
+	gen.Route1 = []Route1Info{{Island: island, Dist: 6}}
+	if ring, _, _ := gen.route2Loop(gen.Route1[0], 60, 60, 0); ring == nil {
+		t.Fatalf("no ring fits around the island")
+	}
+
+	gen.PhaseTransitRoute2Draw(60, 60, rand.New(rand.NewSource(1)))
+
+	if !gen.Route1[0].Loop {
+		t.Fatalf("island was not looped (%s)", gen.PhaseName)
+	}
+	if !enclosedByRoutes(m, island) {
+		t.Errorf("route 2 ring around the island is broken")
+	}
+	for x := 0; x < 60; x++ {
+		for y := 0; y < 60; y++ {
+			if m.Tiles[x][y].Source == SrcRoute2Path && m.Tiles[x][y].Type != W2TileVariableOcean {
+				t.Fatalf("route 2 on non-ocean tile (%d,%d)", x, y)
+			}
+		}
+	}
+
+	// 輪の収まる余白がなければ、その島には輪を描かない
+	gen = newRouteTestGenerator(60, 60)
+	for x := 5; x < 55; x++ {
+		for y := 5; y < 55; y++ {
+			gen.World2.Tiles[x][y] = World2Tile{Type: W2TileSoil, Source: SrcMain}
+		}
+	}
+	gen.Route1 = []Route1Info{{Island: island, Dist: 6}}
+	gen.PhaseTransitRoute2Draw(60, 60, rand.New(rand.NewSource(1)))
+	if gen.Route1[0].Loop {
+		t.Errorf("island inside the continent was looped")
+	}
+}
+
+// 既定の設定で生成全体を流しても、孤立島が1〜2個のマップでは航路2が引かれ、囲んだ島の輪は途切れないこと
+func TestRoute2DrawnByDefaultPipeline(t *testing.T) {
+	drawn := 0
+	for _, seed := range []int64{25, 30, 35} {
+		gen := newTestGenerator(t, DefaultConfig(), seed)
+		for !gen.IsFinished && gen.CurrentStep <= Phase_Transit_Route2_Draw {
+			gen.NextStep()
+		}
+		tiles := 0
+		for x := range gen.World2.Tiles {
+			for _, tile := range gen.World2.Tiles[x] {
+				if tile.Source == SrcRoute2Path {
+					tiles++
+				}
+			}
+		}
+		if gen.Route2Needed && tiles == 0 {
+			t.Errorf("seed %d: route 2 needed but no route 2 tiles", seed)
+		}
+		for _, r := range gen.Route1 {
+			if r.Loop && !enclosedByRoutes(gen.World2, r.Island) {
+				t.Errorf("seed %d: route 2 ring around %v is broken", seed, r.Island)
+			}
+		}
+		if tiles > 0 {
+			drawn++
+		}
+	}
+	if drawn == 0 {
+		t.Errorf("no seed drew route 2 with the default config")
+	}
+}
@@ -0,0 +1,249 @@
+// filename: world2/save_test.go
+package world2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// JSON/バイナリの両形式で保存→読み込みしてタイル・シード・設定が一致すること
+func TestSaveRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.W, cfg.H = 90, 70
+	gen := newTestGenerator(t, cfg, 99)
+	gen.Run()
+	want := gen.SaveData()
+
+	writers := map[string]func(*SaveData, *bytes.Buffer) error{
+		"json":   func(d *SaveData, b *bytes.Buffer) error { return d.WriteJSON(b) },
+		"binary": func(d *SaveData, b *bytes.Buffer) error { return d.WriteBinary(b) },
+	}
+	for name, write := range writers {
+		var buf bytes.Buffer
+		if err := write(want, &buf); err != nil {
+			t.Fatalf("%s: write: %v", name, err)
+		}
+		got, err := ReadSave(&buf)
+		if err != nil {
+			t.Fatalf("%s: read: %v", name, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("%s: round trip mismatch", name)
+		}
+
+		loaded, err := NewGeneratorFromSave(got)
+		if err != nil {
+			t.Fatalf("%s: NewGeneratorFromSave: %v", name, err)
+		}
+		if !loaded.IsFinished || loaded.StartSeed != 99 {
+			t.Errorf("%s: loaded generator not finished or seed lost", name)
+		}
+		if !bytes.Equal(tileBytes(gen.World2), tileBytes(loaded.World2)) {
+			t.Errorf("%s: restored tiles differ", name)
+		}
+	}
+}
+
+// バイナリ形式が JSON より十分小さいこと (RLE が効いていること)
+func TestBinarySaveIsCompact(t *testing.T) {
+	m, err := Generate(DefaultConfig(), 3)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	d := NewSaveData(m, DefaultConfig(), 3)
+	var js, bin bytes.Buffer
+	d.WriteJSON(&js)
+	d.WriteBinary(&bin)
+	if bin.Len()*4 > js.Len() {
+		t.Errorf("binary %d bytes is not compact compared to json %d bytes", bin.Len(), js.Len())
+	}
+}
+
+func TestReadSaveRejectsUnknownVersion(t *testing.T) {
+	d := NewSaveData(NewWorldMap2(40, 40), DefaultConfig(), 1)
+	d.Version = SaveVersion + 1
+	var buf bytes.Buffer
+	d.WriteBinary(&buf)
+	if _, err := ReadSave(&buf); err == nil {
+		t.Fatal("expected error for unknown binary version")
+	}
+	buf.Reset()
+	d.WriteJSON(&buf)
+	if _, err := ReadSave(&buf); err == nil {
+		t.Fatal("expected error for unknown json version")
+	}
+}
+
+// 壊れた・途中で切れたバイナリはパニックせずエラーになること
+func TestReadSaveRejectsCorruptBinary(t *testing.T) {
+	header := func(version, w, h uint16) []byte {
+		b := append([]byte(nil), binaryMagic[:]...)
+		b = binary.LittleEndian.AppendUint16(b, version)
+		b = binary.LittleEndian.AppendUint16(b, w)
+		b = binary.LittleEndian.AppendUint16(b, h)
+		return binary.LittleEndian.AppendUint64(b, 1)
+	}
+	cases := map[string][]byte{
+		"huge config length": binary.AppendUvarint(header(SaveVersion, 40, 40), 1<<62),
+		"huge stats count":   binary.AppendUvarint(append(binary.AppendUvarint(header(SaveVersion, 40, 40), 2), '{', '}'), 1<<62),
+		"zero size":          header(SaveVersion, 0, 0),
+		"too large":          header(SaveVersion, MaxSize+1, MaxSize),
+		"too small":          header(SaveVersion, MinSize-1, MinSize),
+	}
+	for name, b := range cases {
+		if _, err := ReadSave(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	gen := newTestGenerator(t, DefaultConfig(), 5)
+	gen.Run()
+	var buf bytes.Buffer
+	if err := gen.SaveData().WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	full := buf.Bytes()
+	for l := 0; l < len(full); l += 1 + l/64 { // 先頭は1バイトずつ、後ろほど間引いて切る
+		if _, err := ReadSave(bytes.NewReader(full[:l])); err == nil {
+			t.Fatalf("truncated at %d of %d bytes: expected error", l, len(full))
+		}
+	}
+	if _, err := ReadSave(bytes.NewReader(full[:len(full)-1])); err == nil {
+		t.Errorf("missing last byte: expected error")
+	}
+}
+
+// 範囲外のタイルの値や、マップの外・陸地以外にある首都を持つ保存データは、マップに戻す前にエラーになること
+func TestMapRejectsOutOfRangeValues(t *testing.T) {
+	gen := newTestGenerator(t, DefaultConfig(), 6)
+	gen.Run()
+	base := gen.SaveData()
+	if len(base.Capitals) == 0 {
+		t.Fatal("test map has no capitals")
+	}
+	ocean := -1
+	for i, typ := range base.Type {
+		if typ == W2TileVariableOcean {
+			ocean = i
+			break
+		}
+	}
+	cases := map[string]func(d *SaveData){
+		"type":            func(d *SaveData) { d.Type[7] = W2TileShallow + 1 },
+		"source":          func(d *SaveData) { d.Source[7] = SrcRoute2Path + 1 },
+		"elevation":       func(d *SaveData) { d.Elevation[7] = 1 << 16 },
+		"biome":           func(d *SaveData) { d.Biome[7] = BiomeCount },
+		"nation":          func(d *SaveData) { d.Nation[7] = MaxNations + 1 },
+		"settlement":      func(d *SaveData) { d.Settlement[7] = SettlePort + 1 },
+		"capital off map": func(d *SaveData) { d.Capitals[0] = Point{d.Width, 0} },
+		"capital at sea":  func(d *SaveData) { d.Capitals[0] = Point{ocean % d.Width, ocean / d.Width} },
+		"too many capitals": func(d *SaveData) {
+			d.Capitals = make([]Point, MaxNations+1)
+			for i := range d.Capitals {
+				d.Capitals[i] = NoCapital
+			}
+		},
+	}
+	for name, corrupt := range cases {
+		d := *base
+		d.Type = append([]int(nil), base.Type...)
+		d.Source = append([]int(nil), base.Source...)
+		d.Elevation = append([]int(nil), base.Elevation...)
+		d.Biome = append([]int(nil), base.Biome...)
+		d.Nation = append([]int(nil), base.Nation...)
+		d.Settlement = append([]int(nil), base.Settlement...)
+		d.Capitals = append([]Point(nil), base.Capitals...)
+		corrupt(&d)
+		if _, err := NewGeneratorFromSave(&d); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+
+		// JSON を経由しても同じく弾かれる
+		var buf bytes.Buffer
+		if err := d.WriteJSON(&buf); err != nil {
+			t.Fatalf("%s: WriteJSON: %v", name, err)
+		}
+		if got, err := ReadSave(&buf); err == nil {
+			if _, err := got.Map(); err == nil {
+				t.Errorf("%s: json: expected error", name)
+			}
+		}
+	}
+	if _, err := base.Map(); err != nil {
+		t.Errorf("unchanged save: %v", err)
+	}
+}
+
+// 各バージョンで増えたブロック (標高・川・バイオーム・国と首都・集落と道) を持たない古い保存データも、
+// JSON/バイナリの両形式で書いて読み戻すと、そのバージョンまでの内容がそのまま残ること
+func TestOldSaveVersionsRoundTrip(t *testing.T) {
+	gen := newTestGenerator(t, DefaultConfig(), 6)
+	gen.Run()
+	d := gen.SaveData()
+	if len(d.Capitals) == 0 {
+		t.Fatal("test map has no capitals")
+	}
+	for v := 1; v < SaveVersion; v++ {
+		old := *d
+		old.Version = v
+		if v < 2 {
+			old.Elevation = nil
+		}
+		if v < 3 {
+			old.River = nil
+		}
+		if v < 4 {
+			old.Biome = nil
+		}
+		if v < 5 {
+			old.Nation, old.Capitals = nil, nil
+		}
+		if v < 6 {
+			old.Settlement, old.Road = nil, nil
+		}
+		for name, write := range map[string]func(*SaveData, *bytes.Buffer) error{
+			"json":   func(d *SaveData, b *bytes.Buffer) error { return d.WriteJSON(b) },
+			"binary": func(d *SaveData, b *bytes.Buffer) error { return d.WriteBinary(b) },
+		} {
+			var buf bytes.Buffer
+			if err := write(&old, &buf); err != nil {
+				t.Fatalf("v%d %s: write: %v", v, name, err)
+			}
+			got, err := ReadSave(&buf)
+			if err != nil {
+				t.Fatalf("v%d %s: read: %v", v, name, err)
+			}
+			if !reflect.DeepEqual(&old, got) {
+				t.Errorf("v%d %s: round trip mismatch", v, name)
+				continue
+			}
+			if _, err := NewGeneratorFromSave(got); err != nil {
+				t.Errorf("v%d %s: NewGeneratorFromSave: %v", v, name, err)
+			}
+		}
+	}
+}
+
+// 読み込んだ生成器は、同じ段構成で最後まで生成した生成器と同じ完了状態になること
+func TestLoadedGeneratorIsFinished(t *testing.T) {
+	for _, order := range [][]string{nil, {"init", "mask", "soil", "cliffs", "lakes"}} {
+		cfg := DefaultConfig()
+		cfg.Phases = order
+		gen := newTestGenerator(t, cfg, 8)
+		gen.Run()
+		loaded, err := NewGeneratorFromSave(gen.SaveData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !loaded.IsFinished || loaded.PhaseIndex != len(loaded.Phases) || loaded.CurrentStep != gen.CurrentStep {
+			t.Errorf("phases %v: loaded finished %v index %d/%d step %d, want step %d",
+				order, loaded.IsFinished, loaded.PhaseIndex, len(loaded.Phases), loaded.CurrentStep, gen.CurrentStep)
+		}
+		loaded.NextStep()
+		if !loaded.IsFinished || loaded.TimelineLen() != 1 {
+			t.Errorf("phases %v: NextStep after load changed the generator", order)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// filename: world2/seedsearch.go
+package world2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Constraint は指標1つに対する条件 (例: islands=3, lakes>=1, largest>60)
+type Constraint struct {
+	Metric string // MetricNames のいずれか
+	Op     string // "=", "!=", "<", "<=", ">", ">="
+	Value  float64
+}
+
+// constraintOps は長い演算子から順に並べる ("<=" を "<" より先に探す)
+var constraintOps = []string{"<=", ">=", "!=", "=", "<", ">"}
+
+// ParseConstraints は "islands=3, lakes>=1, largest>60" のような条件の並び (カンマか空白区切り) を読む
+// 値の後ろの % は無視する ("largest>60%" も書ける)
+func ParseConstraints(s string) ([]Constraint, error) {
+	var cs []Constraint
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		var c Constraint
+		for _, op := range constraintOps {
+			if i := strings.Index(f, op); i > 0 {
+				c.Metric, c.Op = strings.ToLower(f[:i]), op
+				v, err := strconv.ParseFloat(strings.TrimSuffix(f[i+len(op):], "%"), 64)
+				if err != nil {
+					return nil, fmt.Errorf("Constraint %q: value must be a number", f)
+				}
+				c.Value = v
+				break
+			}
+		}
+		if c.Op == "" {
+			return nil, fmt.Errorf("Constraint %q: must be metric, operator, value (e.g. lakes>=1)", f)
+		}
+		if _, ok := (Metrics{}).Value(c.Metric); !ok {
+			return nil, fmt.Errorf("Constraint %q: metric must be one of %s", f, strings.Join(MetricNames, ", "))
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// String は条件を ParseConstraints で読める形で返す
+func (c Constraint) String() string {
+	return c.Metric + c.Op + strconv.FormatFloat(c.Value, 'g', -1, 64)
+}
+
+// Match は指標が条件を満たすかを返す
+func (c Constraint) Match(m Metrics) bool {
+	v, _ := m.Value(c.Metric)
+	switch c.Op {
+	case "=":
+		return v == c.Value
+	case "!=":
+		return v != c.Value
+	case "<":
+		return v < c.Value
+	case "<=":
+		return v <= c.Value
+	case ">":
+		return v > c.Value
+	case ">=":
+		return v >= c.Value
+	}
+	return false
+}
+
+// MatchAll は指標がすべての条件を満たすかを返す
+func MatchAll(cs []Constraint, m Metrics) bool {
+	for _, c := range cs {
+		if !c.Match(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrSeedNotFound は決められた回数のうちに条件を満たすシードがなかった時のエラー
+var ErrSeedNotFound = errors.New("no seed meets the constraints")
+
+// ErrSearchCanceled は探索を途中で止めた時のエラー
+var ErrSearchCanceled = errors.New("seed search canceled")
+
+// SearchSeed は start から1ずつ増やしたシードで最後まで生成し、条件をすべて満たした最初の生成器を返す
+// tries 回で見つからなければ ErrSeedNotFound、stop が閉じられたら ErrSearchCanceled を返す (stop は nil でもよい)
+// onTry は試したシードごとに呼ばれる (nil でもよい)
+func SearchSeed(cfg GenConfig, cs []Constraint, start int64, tries int, stop <-chan struct{}, onTry func(seed int64, m Metrics)) (*World2Generator, error) {
+	for i := 0; i < tries; i++ {
+		select {
+		case <-stop:
+			return nil, ErrSearchCanceled
+		default:
+		}
+		seed := start + int64(i)
+		gen, err := NewGenerator(cfg, seed)
+		if err != nil {
+			return nil, err
+		}
+		gen.Run()
+		m := gen.World2.Metrics()
+		if onTry != nil {
+			onTry(seed, m)
+		}
+		if MatchAll(cs, m) {
+			return gen, nil
+		}
+	}
+	return nil, ErrSeedNotFound
+}
+
+// SeedSearch は SearchSeed を別の goroutine で進める (画面から使う)
+type SeedSearch struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	mu    sync.Mutex
+	tried int
+	seed  int64 // 最後に試したシード
+	gen   *World2Generator
+	err   error
+}
+
+// StartSeedSearch は条件を満たすシードを別の goroutine で探し始める
+func StartSeedSearch(cfg GenConfig, cs []Constraint, start int64, tries int) *SeedSearch {
+	s := &SeedSearch{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		gen, err := SearchSeed(cfg, cs, start, tries, s.stop, func(seed int64, m Metrics) {
+			s.mu.Lock()
+			s.tried++
+			s.seed = seed
+			s.mu.Unlock()
+		})
+		s.mu.Lock()
+		s.gen, s.err = gen, err
+		s.mu.Unlock()
+	}()
+	return s
+}
+
+// Cancel は探索を止める。試している最中のシードは最後まで生成してから止まる
+func (s *SeedSearch) Cancel() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// Done は探索が終わったか (見つかったか、見つからなかったか、止まったか) を返す
+func (s *SeedSearch) Done() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Tried は試したシードの数と、最後に試したシードを返す
+func (s *SeedSearch) Tried() (int, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tried, s.seed
+}
+
+// Result は探索が終わるまで待ち、見つかった生成器 (最後まで生成済み) かエラーを返す
+func (s *SeedSearch) Result() (*World2Generator, error) {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen, s.err
+}
@@ -0,0 +1,157 @@
+// filename: world2/settings.go
+package world2
+
+import (
+	"bufio"
+	"fmt"
+	"os" // 追加
+	"strconv"
+	"strings"
+)
+
+// LoadSettings は settings.txt からキーと値を読み込み、マップとして返す
+func LoadSettings(filename string) (map[string]string, error) {
+	settings := make(map[string]string)
+	
+	file, err := os.Open(filename)
+	if err != nil {
+		// ファイルが存在しない場合はエラーとしない (デフォルト値を使用する)
+		if os.IsNotExist(err) {
+			return settings, nil 
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		line = strings.TrimSpace(line)
+		
+		// コメント行または空行をスキップ
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Key: Value の形式で分割
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 {
+			key := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			settings[key] = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	
+	return settings, nil
+}
+
+// ApplyIntSetting は設定マップから整数値を読み込み、target に適用する
+func ApplyIntSetting(settings map[string]string, key string, target *int) {
+	if valStr, ok := settings[key]; ok {
+		if valInt, err := strconv.Atoi(valStr); err == nil {
+			*target = valInt
+		} else {
+			fmt.Printf("Warning: Setting '%s' is not an integer: %v\n", key, err)
+		}
+	}
+}
+
+// ApplyInt64Setting は設定マップから64bit整数値 (シード等) を読み込み、target に適用する
+func ApplyInt64Setting(settings map[string]string, key string, target *int64) {
+	if valStr, ok := settings[key]; ok {
+		if valInt, err := strconv.ParseInt(valStr, 10, 64); err == nil {
+			*target = valInt
+		} else {
+			fmt.Printf("Warning: Setting '%s' is not an integer: %v\n", key, err)
+		}
+	}
+}
+
+// ApplyFloatSetting は設定マップから浮動小数値を読み込み、target に適用する
+func ApplyFloatSetting(settings map[string]string, key string, target *float64) {
+	if valStr, ok := settings[key]; ok {
+		if valFloat, err := strconv.ParseFloat(valStr, 64); err == nil {
+			*target = valFloat
+		} else {
+			fmt.Printf("Warning: Setting '%s' is not a float: %v\n", key, err)
+		}
+	}
+}
+
+// ApplyListSetting は設定マップからカンマ区切りの文字列リストを読み込み、target に適用する
+func ApplyListSetting(settings map[string]string, key string, target *[]string) {
+	if valStr, ok := settings[key]; ok {
+		var list []string
+		for _, s := range strings.Split(valStr, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		*target = list
+	}
+}
+
+// ApplyBoolSetting は設定マップから真偽値を読み込み、target に適用する
+func ApplyBoolSetting(settings map[string]string, key string, target *bool) {
+	if valStr, ok := settings[key]; ok {
+		valStr = strings.ToLower(valStr)
+		if valStr == "true" || valStr == "on" || valStr == "1" {
+			*target = true
+		} else if valStr == "false" || valStr == "off" || valStr == "0" {
+			*target = false
+		} else {
+			fmt.Printf("Warning: Setting '%s' is not a boolean: %s\n", key, valStr)
+		}
+	}
+}
+
+// DefaultConfig は UI 起動時と同じ初期値の GenConfig を返す
+func DefaultConfig() GenConfig {
+	return GenConfig{
+		MinPct: 20, MaxPct: 28, W: DefaultWidth, H: DefaultHeight,
+		TransitDist: 15,
+		VastOcean: 25, IslandBound: 15,
+		MainType: MaskClassic, SubType: MaskClassic, Ratio: 10,
+		Centering: true,
+		CliffInit: 10.0, CliffDec: 0.1, ShallowDec: 0.25,
+		CliffPathLen: 5,
+		ForceSwitch: 5,
+		RiverCount: 6, RiverMinLen: 8,
+		NationCount: 5,
+		TownCount:   12,
+	}
+}
+
+// ApplySettings は settings.txt の値を GenConfig に適用する (キー名は Game.ApplySettings と共通)
+func (c *GenConfig) ApplySettings(settings map[string]string) {
+	ApplyIntSetting(settings, "SoilMin", &c.MinPct)
+	ApplyIntSetting(settings, "SoilMax", &c.MaxPct)
+	ApplyIntSetting(settings, "W2Width", &c.W)
+	ApplyIntSetting(settings, "W2Height", &c.H)
+	ApplyIntSetting(settings, "TransitDist", &c.TransitDist)
+	ApplyIntSetting(settings, "VastOceanSize", &c.VastOcean)
+	ApplyIntSetting(settings, "IslandBoundSize", &c.IslandBound)
+	ApplyIntSetting(settings, "MapRatio", &c.Ratio)
+	ApplyIntSetting(settings, "MainType", &c.MainType)
+	ApplyIntSetting(settings, "SubType", &c.SubType)
+	ApplyIntSetting(settings, "CliffPathLen", &c.CliffPathLen)
+	ApplyIntSetting(settings, "ForceSwitch", &c.ForceSwitch)
+	ApplyIntSetting(settings, "RiverCount", &c.RiverCount)
+	ApplyIntSetting(settings, "RiverMinLen", &c.RiverMinLen)
+	ApplyIntSetting(settings, "NationCount", &c.NationCount)
+	ApplyIntSetting(settings, "TownCount", &c.TownCount)
+
+	ApplyFloatSetting(settings, "CliffInitVal", &c.CliffInit)
+	ApplyFloatSetting(settings, "CliffDec", &c.CliffDec)
+	ApplyFloatSetting(settings, "ShallowDec", &c.ShallowDec)
+
+	ApplyBoolSetting(settings, "Centering", &c.Centering)
+	ApplyBoolSetting(settings, "IslandShallow", &c.IslandShallow)
+
+	ApplyListSetting(settings, "Phases", &c.Phases)
+	ApplyListSetting(settings, "DisablePhases", &c.DisablePhases)
+}
@@ -0,0 +1,154 @@
+// filename: world2/settlement_test.go
+package world2
+
+import "testing"
+
+// 集落は陸地にあり、首都は都市、港は航路に接し、道で行ける集落どうしは道でつながっていること
+func TestSettlementsConnectedByRoads(t *testing.T) {
+	ports := 0
+	for _, seed := range []int64{1, 2, 3, 4} {
+		gen := newTestGenerator(t, DefaultConfig(), seed)
+		gen.Run()
+		m := gen.World2
+		w, h := m.Width, m.Height
+		for _, c := range m.Capitals {
+			if m.Tiles[c.X][c.Y].Settlement != SettleCity {
+				t.Errorf("seed %d: capital (%d,%d) is settlement %d", seed, c.X, c.Y, m.Tiles[c.X][c.Y].Settlement)
+			}
+		}
+
+		land := components(m.Tiles, w, h, func(t World2Tile) bool { return roadCost(t) != roadCostBlocked })
+		roads := components(m.Tiles, w, h, func(t World2Tile) bool { return t.IsRoad || t.Settlement != SettleNone })
+		var settles []int
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				tile := m.Tiles[x][y]
+				if tile.IsRoad && roadCost(World2Tile{Type: tile.Type, IsLake: tile.IsLake}) == roadCostBlocked {
+					t.Fatalf("seed %d: road on impassable tile (%d,%d) type %d", seed, x, y, tile.Type)
+				}
+				if tile.Settlement == SettleNone {
+					continue
+				}
+				if tile.Type != W2TileSoil && tile.Type != W2TileTransit {
+					t.Fatalf("seed %d: settlement on tile type %d at (%d,%d)", seed, tile.Type, x, y)
+				}
+				if tile.Settlement == SettlePort {
+					ports++
+					touches := false
+					for _, d := range []Point{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+						nx, ny := x+d.X, y+d.Y
+						if nx >= 0 && nx < w && ny >= 0 && ny < h && isSeaRoute(m.Tiles[nx][ny]) {
+							touches = true
+						}
+					}
+					if !touches {
+						t.Errorf("seed %d: port (%d,%d) does not touch a sea route", seed, x, y)
+					}
+				}
+				settles = append(settles, y*w+x)
+			}
+		}
+		if len(settles) < len(m.Capitals)+DefaultConfig().TownCount/2 {
+			t.Errorf("seed %d: only %d settlements", seed, len(settles))
+		}
+		for _, a := range settles {
+			for _, b := range settles {
+				if land[a] == land[b] && roads[a] != roads[b] {
+					t.Fatalf("seed %d: settlements (%d,%d) and (%d,%d) share land but no road", seed, a%w, a/w, b%w, b/w)
+				}
+			}
+		}
+	}
+	if ports == 0 {
+		t.Errorf("no ports on any seed")
+	}
+}
+
+// 道は崖を避けて回り道し、浅瀬を渡しとして渡ること
+func TestBuildRoadPrefersDetoursAndFords(t *testing.T) {
+	rows := []string{
+		"#####^####",
+		"#####^####",
+		"#####^####",
+		"##########",
+		"..........",
+		"#,,,,,,,,#",
+		"#########.",
+	}
+	w, h := len(rows[0]), len(rows)
+	tiles := make([][]World2Tile, w)
+	for x := range tiles {
+		tiles[x] = make([]World2Tile, h)
+		for y := range tiles[x] {
+			switch rows[y][x] {
+			case '#':
+				tiles[x][y] = World2Tile{Type: W2TileSoil}
+			case '^':
+				tiles[x][y] = World2Tile{Type: W2TileCliff}
+			case ',':
+				tiles[x][y] = World2Tile{Type: W2TileShallow}
+			default:
+				tiles[x][y] = World2Tile{Type: W2TileVariableOcean}
+			}
+		}
+	}
+
+	network := make([]bool, w*h)
+	network[2*w+9] = true
+	if _, ok := buildRoad(tiles, w, h, Point{0, 2}, network); !ok {
+		t.Fatalf("no road across the cliff ridge")
+	}
+	for y := 0; y < 3; y++ {
+		if tiles[5][y].IsRoad {
+			t.Errorf("road climbs the cliff at (5,%d) instead of going around", y)
+		}
+	}
+	if !tiles[5][3].IsRoad {
+		t.Errorf("road does not use the gap at (5,3)")
+	}
+
+	// 南の土地は海で隔てられている間は道がつながらず、浅瀬でつながれば渡しとして渡る
+	if _, ok := buildRoad(tiles, w, h, Point{0, 6}, network); ok {
+		t.Errorf("road crosses open sea")
+	}
+	tiles[4][4] = World2Tile{Type: W2TileShallow}
+	if _, ok := buildRoad(tiles, w, h, Point{0, 6}, network); !ok || !tiles[4][4].IsRoad {
+		t.Errorf("road does not ford the shallows")
+	}
+}
+
+func TestSettlementsDisabled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TownCount = 0
+	gen := newTestGenerator(t, cfg, 2)
+	gen.Run()
+	if got := executedName(gen, Phase_Settlements); got != "Settlements (Skipped)" {
+		t.Errorf("phase %q, want Settlements (Skipped)", got)
+	}
+	for x := 0; x < gen.World2.Width; x++ {
+		for y := 0; y < gen.World2.Height; y++ {
+			if tile := gen.World2.Tiles[x][y]; tile.IsRoad || tile.Settlement != SettleNone {
+				t.Fatalf("(%d,%d) has road %v settlement %d", x, y, tile.IsRoad, tile.Settlement)
+			}
+		}
+	}
+}
+
+// 手描きで外れた首都の枠 (NoCapital) は飛ばし、残りの首都だけを都市にすること
+func TestSettlementsSkipDroppedCapital(t *testing.T) {
+	gen := newTestGenerator(t, DefaultConfig(), 6)
+	for gen.CurrentStep < Phase_Settlements {
+		gen.NextStep()
+	}
+	if len(gen.World2.Capitals) < 2 {
+		t.Fatalf("test map has %d capitals", len(gen.World2.Capitals))
+	}
+	c := gen.World2.Capitals[0]
+	gen.dropCapital(c.X, c.Y)
+	gen.NextStep()
+	for _, c := range gen.World2.Capitals[1:] {
+		if gen.World2.Tiles[c.X][c.Y].Settlement != SettleCity {
+			t.Errorf("capital (%d,%d) is settlement %d", c.X, c.Y, gen.World2.Tiles[c.X][c.Y].Settlement)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// filename: world2/types.go
+package world2
+
+import (
+	"math/rand"
+)
+
+// World2 のタイルサイズ制約
+const (
+	DefaultWidth  = 150
+	DefaultHeight = 100
+	MinSize       = 40
+	MaxSize       = 500
+)
+
+// マスク形状 (GenerateMask の typeID)
+const (
+	MaskClassic       = 1 // クラシック
+	MaskCentralIsland = 2 // 中央島
+	MaskLeftCont      = 3 // 左大陸
+	MaskUpperCont     = 4 // 上方大陸
+	MaskRightCont     = 5 // 右大陸
+	MaskRing          = 6 // 回・大陸
+	MaskArchipelago   = 7 // 諸島
+	MaskLinkedIsles   = 8 // 連結諸島
+	MaskMagatama      = 9 // 勾玉
+
+	MinMaskType = MaskClassic
+	MaxMaskType = MaskMagatama
+)
+
+const (
+	W2TileVariableOcean = 0
+	W2TileSoil          = 1
+	W2TileFixedOcean    = 2
+	W2TileTransit       = 3
+	W2TileCliff         = 4
+	W2TileShallow       = 5
+)
+
+const (
+	SrcNone          = 0
+	SrcMain          = 1
+	SrcSub           = 2
+	SrcMix           = 3
+	SrcBridge        = 4
+	SrcIsland        = 5
+	SrcTransitPath   = 6
+	SrcBRouteIsland  = 7  // B航路の経由島（緑）
+	SrcBRoutePath    = 8  // B航路の航路（暗緑）
+	SrcRoute2Path    = 9  // 航路2 (孤立島を囲む円形航路)（紫）
+)
+
+// World2 Generation Phases (Step ID)
+const (
+	Phase_Init             = 0
+	Phase_MaskGen          = 1
+	Phase_SoilStart        = 2
+	Phase_SoilProgressEnd  = 11
+	Phase_Bridge           = 13
+	Phase_Centering        = 14
+	Phase_IslandsQuad      = 15
+	Phase_IslandsRand      = 16
+
+	// Transit Phase の拡張
+	Phase_Transit_Start    = 17
+	Phase_IslandShallowAdjust = 18
+	Phase_Transit_Route1   = 19
+	Phase_Transit_Route2_Calc = 20
+	Phase_Transit_Route2_Draw = 21
+
+	Phase_CliffsShallows   = 22
+	Phase_LakesFinal       = 23
+	Phase_Elevation        = 24
+	Phase_Rivers           = 25
+	Phase_Climate          = 26
+	Phase_Nations          = 27
+	Phase_Settlements      = 28
+)
+
+type Rect struct {
+	X, Y, W, H int
+}
+
+type Point struct {
+	X, Y int
+}
+
+// NoCapital は手描きで塗りつぶされて外れた首都の枠 (枠を詰めないので Capitals[i] は Nation i+1 のまま)
+var NoCapital = Point{-1, -1}
+
+type World2Tile struct {
+	Type      int
+	Source    int
+	IsLake    bool
+	IsRiver   bool   // 川 (土のタイルに重ねる)。PhaseRivers で設定
+	Elevation int16  // 標高 (陸地は 1..ElevationMax、海と湖は 0 以下で岸からの深さ)。PhaseElevation で設定
+	Walks     uint16 // 土の配置でウォーカーが通過した回数 (標高の計算に使う)
+	Biome     uint8  // Biome* (水域は BiomeOcean)。PhaseClimate で設定
+	Nation    uint8  // 所属する国 (1..、0 は無所属と水域)。PhaseNations で設定
+	Settlement uint8 // Settle* (0 は集落なし)。PhaseSettlements で設定
+	IsRoad    bool   // 道 (陸地と、浅瀬の渡し)。PhaseSettlements で設定
+}
+
+// バイオーム (World1 の WorldTile.Biome と同じ番号。5 以降は World2 のみ)
+const (
+	BiomeOcean    = 0 // TexW_Ocean
+	BiomePlains   = 1 // TexW_Plains
+	BiomeForest   = 2 // TexW_Forest
+	BiomeDesert   = 3 // TexW_Desert
+	BiomeMountain = 4 // TexW_Mountain
+	BiomeTundra   = 5
+	BiomeSwamp    = 6
+
+	BiomeCount = 7
+)
+
+// 集落の種類 (World2Tile.Settlement)
+const (
+	SettleNone = 0
+	SettleTown = 1 // 町
+	SettleCity = 2 // 都市 (首都と河口)
+	SettlePort = 3 // 港 (航路が陸地に接する所)
+)
+
+// WorldMap2 は生成結果のマップデータ (描画状態は持たない)
+type WorldMap2 struct {
+	Width, Height    int
+	Tiles            [][]World2Tile
+	StatsInfo        []string
+	PinkRects        []Rect
+	Capitals         []Point // 国の首都 (Capitals[i] が Nation i+1。手描きで塗った首都は NoCapital になる)
+}
+
+type GenSnapshot struct {
+	Tiles     [][]World2Tile // キーフレームのみ。それ以外は nil (SnapshotTiles で復元する)
+	Diff      []TileChange   // 直前のスナップショットからの変化 (キーフレームでは nil)
+	PhaseName string
+	StepID    int
+
+	NewSoils         map[int]bool
+	PinkRects        []Rect
+	Capitals         []Point
+	StatsInfo        []string
+	Walkers          []struct{x, y int}
+	CurrentSoilCount int
+	Multiplier       float64
+	Excluded         map[int]bool
+	CurrentSeed      int64
+
+	CliffStreak   int
+	ShallowStreak int
+
+	TotalRoute1Dist float64
+	Route1          []Route1Info
+	Route2Needed    bool
+	LastTargetSoil  int
+
+	// マスクは PhaseMaskGen が毎回作り直し、ほかの段は書き換えないので複製せずに共有する (マスクの段より前は nil)
+	MaskMain, MaskSub, FinalMask [][]float64
+	TargetSoilCount              int
+
+	PhaseIndex int
+}
+
+// Route1Info は航路1でつながった孤立島1つ分の情報
+type Route1Info struct {
+	Island Rect    // 孤立島の外接矩形
+	Dist   float64 // 孤立島に接する航路のタイル数
+	Loop   bool    // 航路2の輪で囲んだか (route2_draw が設定する。陸地にかからない輪が収まらなければ偽)
+}
+
+type World2Generator struct {
+	CurrentStep int // 次に実行する段の ID (Phase_*)
+	IsFinished  bool
+	PhaseName   string
+	History     []GenSnapshot
+	Redo        []GenSnapshot  // UndoStep/JumpTo で戻した先のスナップショット (古い順)
+	histTip     [][]World2Tile // History 末尾時点のタイル (差分の計算と Undo の復元に使う)
+
+	Phases     []Phase // 実行する段の並び (GenConfig から BuildPhases で作成)
+	PhaseIndex int     // 次に実行する段の Phases 上の位置
+
+	World2 *WorldMap2
+
+	Rng             *rand.Rand
+	StartSeed       int64 // NewGenerator に渡された初期シード (再現用)
+	CurrentSeed     int64
+
+	MaskMain        [][]float64
+	MaskSub         [][]float64
+	FinalMask       [][]float64
+
+	Walkers         []struct{ x, y int }
+	TargetSoilCount int
+	CurrentSoilCount int
+	Config          GenConfig
+
+	NewSoils map[int]bool
+
+	Multiplier float64
+	Excluded   map[int]bool
+
+	CliffStreak   int
+	ShallowStreak int
+
+	TotalRoute1Dist float64      // 航路1 (A/B航路と経由島) の総タイル数
+	Route1          []Route1Info // 航路でつながった孤立島ごとの実測値
+	Route2Needed    bool         // route2_calc で航路2を描くと判定されたか
+	LastTargetSoil  int // MaskGen で決定した目標土地率 (%)
+
+	SafetyCapHits []string // 繰り返しが安全のための上限に達して打ち切られた箇所 ("段ID 箇所")。Undo では消えない
+
+	pendingEdit map[int]bool // Paint で変えたがまだ CommitEdit していないタイル (y*w+x)
+
+	interrupt <-chan struct{} // 閉じられたら長い段を途中で打ち切る (RunInBackground の Cancel)
+	aborted   bool            // 実行中の段が interrupt で打ち切られた (NextStep が段の前の状態に戻す)
+}
+
+type GenConfig struct {
+	MinPct, MaxPct, W, H, TransitDist, Ratio int
+	VastOcean, IslandBound int
+	Centering bool
+	IslandShallow bool // B航路の経由島が円状に並んだ内側を浅瀬化する (PhaseIslandShallowAdjust)
+	CliffInit, CliffDec, ShallowDec float64
+	CliffPathLen, ForceSwitch int
+	RiverCount, RiverMinLen int // 川の本数 (0 で川なし) と、1本の最小の長さ (マス)
+	NationCount int // 国の数 (0 で国なし、最大 MaxNations)
+	TownCount   int // 首都・港以外に置く町と都市の数 (0 で集落と道なし)
+
+	Phases        []string // 段の実行順 (Key)。空なら DefaultPhaseOrder
+	DisablePhases []string // 無効にする段 (Key)
+	MainType, SubType int
+}
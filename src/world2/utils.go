@@ -1,11 +1,9 @@
-// filename: world2/world2_utils.go
-package main
+// filename: world2/utils.go
+package world2
 
 import (
 	"math"
 	"math/rand"
-
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
 // GenerateMask: タイプごとの形状マスクを生成 (0.0~1.0)
@@ -52,32 +50,43 @@ func GenerateMask(w, h, typeID int, rng *rand.Rand) [][]float64 {
 	return mask
 }
 
-// UpdateMaskImage は FinalMask を Ebiten Image に変換する
-func (gen *World2Generator) UpdateMaskImage(w, h int) {
-	if gen.MaskImage == nil {
-		gen.MaskImage = ebiten.NewImage(w, h)
+// BlendMasks: Mask = Main × Ratio/10 + Sub × (1 − Ratio/10) で合成したマスクを返す
+func BlendMasks(main, sub [][]float64, ratio int) [][]float64 {
+	r := float64(ratio) / 10.0
+	mask := make([][]float64, len(main))
+	for x := range main {
+		mask[x] = make([]float64, len(main[x]))
+		for y := range main[x] {
+			mask[x][y] = main[x][y]*r + sub[x][y]*(1-r)
+		}
 	}
-	gen.MaskImage.Clear()
-	
-	pix := make([]byte, w*h*4)
-	for y := 0; y < h; y++ {
-		for x := 0; x < w; x++ {
-			idx := (y*w + x) * 4
-			if gen.FinalMask != nil {
-				val := gen.FinalMask[x][y]
-				if val > 0 {
-					gray := uint8(val * 255)
-					pix[idx] = gray
-					pix[idx+1] = gray
-					pix[idx+2] = gray
-					pix[idx+3] = 100
-				} else {
-					pix[idx+3] = 0
-				}
-			} else {
-				pix[idx+3] = 0
+	return mask
+}
+
+// Bresenham4: (x0,y0) から (x1,y1) までの線上のマスを順に plot に渡す
+// 斜めに進む箇所は x 方向のマスを挟み、4近傍でつながった線にする
+func Bresenham4(x0, y0, x1, y1 int, plot func(x, y int)) {
+	dx, dy := x1-x0, y1-y0
+	sx, sy := 1, 1
+	if dx < 0 { sx, dx = -1, -dx }
+	if dy < 0 { sy, dy = -1, -dy }
+	err := dx - dy
+	x, y := x0, y0
+	plot(x, y)
+	for x != x1 || y != y1 {
+		e2 := 2 * err
+		stepX, stepY := e2 > -dy, e2 < dx
+		if stepX {
+			err -= dy
+			x += sx
+		}
+		if stepY {
+			err += dx
+			if stepX {
+				plot(x, y)
 			}
+			y += sy
 		}
+		plot(x, y)
 	}
-	gen.MaskImage.WritePixels(pix)
-}
\ No newline at end of file
+}